@@ -2,25 +2,27 @@ package asm
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 
 	"go.creack.net/corewar/asm/parser"
 	"go.creack.net/corewar/op"
 )
 
-func Compile(inputName, inputData string, strict bool) ([]byte, *parser.Program, error) {
-	// Parse the input.
+// Compile assembles the given source.
+// Errors are returned as a parser.ErrorList with all of them, along with the warnings,
+// sorted by position. On success, the warnings are in the program, see Program.Warnings.
+func Compile(inputName, inputData string, warnOpts parser.WarningOptions) ([]byte, *parser.Program, error) {
+	var errs parser.ErrorList
+
+	// Parse the input, keep going on error to report the encoding errors as well.
 	p := parser.NewParser(inputName, inputData)
-	if err := p.Parse(); err != nil {
-		return nil, nil, fmt.Errorf("failed to parse: %w", err)
-	}
+	errs.Add(p.Parse())
 
 	// Encode the program.
-	pr := parser.NewProgram(p, strict)
+	pr := parser.NewProgram(p, warnOpts)
 	program, err := pr.Encode()
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to encode program: %w", err)
-	}
+	errs.Add(err)
 
 	// Create the header.
 	header := op.Header{
@@ -29,15 +31,27 @@ func Compile(inputName, inputData string, strict bool) ([]byte, *parser.Program,
 	}
 	name := p.GetDirective(op.NameCmdString)
 	if name == "" {
-		return nil, nil, fmt.Errorf("missing program name")
+		errs.Add(&parser.Diagnostic{Pos: parser.Position{File: inputName}, Err: errors.New("missing program name")})
 	}
 	copy(header.ProgName[:], []byte(name))
 	comment := p.GetDirective(op.CommentCmdString)
 	if len(comment) > op.CommentLength {
-		return nil, nil, fmt.Errorf("comment exceeds maximum length")
+		errs.Add(p.ErrorAt(p.LookupDirective(op.CommentCmdString).Position, fmt.Errorf("comment exceeds maximum length of %d", op.CommentLength)))
 	}
 	copy(header.Comment[:], []byte(comment))
 
+	// A champion with nothing but its header is most likely a mistake,
+	// unless the code is missing because of the errors.
+	if len(errs) == 0 && pr.Size() == 0 {
+		errs.Add(pr.Warn(parser.Position{File: inputName}, parser.WarnEmptyCode, parser.ErrEmptyCode))
+	}
+
+	if len(errs) > 0 {
+		errs = append(errs, pr.Warnings()...)
+		errs.Sort()
+		return nil, nil, errs
+	}
+
 	buf := bytes.NewBuffer(nil)
 
 	// Write the magic number.
@@ -0,0 +1,198 @@
+package asm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.creack.net/corewar/asm/parser"
+)
+
+// header is the start of the test sources, the code starts on line 4.
+const header = ".name \"test\"\n.comment \"test\"\n\n"
+
+// diagnostics returns the diagnostics as line:col: code, the code being empty for plain errors.
+func diagnostics(list parser.ErrorList) []string {
+	out := make([]string, 0, len(list))
+	for _, d := range list {
+		out = append(out, fmt.Sprintf("%d:%d: %s", d.Pos.Line, d.Pos.Column, d.Code))
+	}
+	return out
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string // See diagnostics.
+	}{
+		{
+			name: "all errors",
+			src:  header + "\tlive\t%1\n\tfoo\tr1\n\tld\t%1, r2, r3\nl:\tzjmp\t%:nope\n\tst\tr1\n",
+			want: []string{"5:2: ", "6:2: ", "7:9: ", "8:2: "},
+		},
+		{
+			name: "duplicate label",
+			src:  header + "l:\n\tlive\t%1\nl:\n\tzjmp\t%:l\n",
+			want: []string{"6:1: "},
+		},
+		{
+			name: "missing name",
+			src:  ".comment \"test\"\n\n\tlive\t%1\n",
+			want: []string{"0:0: "},
+		},
+		{
+			name: "errors and warnings sorted",
+			src:  header + "\tst\tr1, r17\n\tfoo\n\tst\tr1, r0\n\tbar\n",
+			want: []string{"4:9: W001", "5:2: ", "6:9: W001", "7:2: "},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Compile("test.s", tt.src, parser.WarningOptions{})
+			var list parser.ErrorList
+			if !errors.As(err, &list) {
+				t.Fatalf("Unexpected error: %v, expected a parser.ErrorList.", err)
+			}
+			if got := diagnostics(list); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Fatalf("Unexpected diagnostics:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			for _, d := range list {
+				if d.Pos.File != "test.s" {
+					t.Errorf("Diagnostic %q without the file name.", d)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		err     string
+		excerpt string
+	}{
+		{
+			name:    "unknown instruction",
+			src:     header + "\tlive\t%1\n\tfoo\tr1\n",
+			err:     `test.s:5:2: unknown instruction "foo"`,
+			excerpt: "\tfoo\tr1\n\t^\n",
+		},
+		{
+			name:    "parameter count",
+			src:     header + "\tst\tr1\n",
+			err:     "test.s:4:2: invalid instruction <st (r1)>: expected 2 parameters, got 1",
+			excerpt: "\tst\tr1\n\t^\n",
+		},
+		{
+			name:    "unknown label",
+			src:     header + "l:\tzjmp\t%:nope\n",
+			err:     `test.s:4:9: unknown label ":nope"`,
+			excerpt: "l:\tzjmp\t%:nope\n  \t    \t^\n",
+		},
+		{
+			name:    "duplicate label",
+			src:     header + "l:\n\tlive\t%1\nl:\n",
+			err:     `test.s:6:1: duplicate label "l", first defined at test.s:4:1`,
+			excerpt: "l:\n^\n",
+		},
+		{
+			name: "missing name",
+			src:  ".comment \"test\"\n\n\tlive\t%1\n",
+			err:  "test.s: missing program name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Compile("test.s", tt.src, parser.WarningOptions{})
+			var list parser.ErrorList
+			if !errors.As(err, &list) || len(list) != 1 {
+				t.Fatalf("Unexpected error: %v, expected a single diagnostic.", err)
+			}
+			d := list[0]
+			if d.Error() != tt.err {
+				t.Fatalf("Unexpected error: %q, expected %q.", d, tt.err)
+			}
+			if got := d.Excerpt(); got != tt.excerpt {
+				t.Fatalf("Unexpected excerpt:\n%s\nexpected:\n%s", got, tt.excerpt)
+			}
+		})
+	}
+}
+
+func TestCompileWarnings(t *testing.T) {
+	src := header + "\tlive\t%1\n\tst\tr1, r17\n.code 01 02\n"
+	tests := []struct {
+		name     string
+		opts     parser.WarningOptions
+		want     []string // See diagnostics.
+		severity parser.Severity
+	}{
+		{name: "default", want: []string{"5:9: W001", "6:1: W002"}, severity: parser.SeverityWarning},
+		{name: "Werror", opts: parser.WarningOptions{Error: true}, want: []string{"5:9: W001", "6:1: W002"}, severity: parser.SeverityError},
+		{name: "Wno", opts: parser.WarningOptions{Disabled: map[string]bool{parser.WarnRegister: true}}, want: []string{"6:1: W002"}, severity: parser.SeverityWarning},
+		{name: "Wno all", opts: parser.WarningOptions{Error: true, Disabled: map[string]bool{parser.WarnRegister: true, parser.WarnCodeExtend: true}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, pr, err := Compile("test.s", src, tt.opts)
+			var list parser.ErrorList
+			if tt.opts.Error && len(tt.want) > 0 {
+				if !errors.As(err, &list) {
+					t.Fatalf("Unexpected error: %v, expected a parser.ErrorList.", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Failed to compile: %s.", err)
+				}
+				list = pr.Warnings()
+			}
+			if got := diagnostics(list); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Fatalf("Unexpected diagnostics:\n%s\nexpected:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			for _, d := range list {
+				if d.Severity != tt.severity {
+					t.Errorf("Diagnostic %q is a %s, expected a %s.", d, d.Severity, tt.severity)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileEmpty(t *testing.T) {
+	buf, pr, err := Compile("test.s", header, parser.WarningOptions{})
+	if err != nil {
+		t.Fatalf("Failed to compile: %s.", err)
+	}
+	if want := "test.s: warning: no code after header (W005)"; len(pr.Warnings()) != 1 || pr.Warnings()[0].Error() != want {
+		t.Fatalf("Unexpected warnings: %v, expected %q.", pr.Warnings(), want)
+	}
+
+	// The same when loading the binary.
+	p := &parser.Program{}
+	if _, err := p.Decode(buf, parser.WarningOptions{}); err != nil {
+		t.Fatalf("Failed to decode: %s.", err)
+	}
+	if w := p.Warnings(); len(w) != 1 || w[0].Code != parser.WarnEmptyCode {
+		t.Fatalf("Unexpected decode warnings: %v.", w)
+	}
+
+	opts := parser.WarningOptions{Error: true}
+	var list parser.ErrorList
+	if _, _, err := Compile("test.s", header, opts); !errors.As(err, &list) || len(list) != 1 || !errors.Is(list[0], parser.ErrEmptyCode) {
+		t.Fatalf("Unexpected error: %v, expected %s.", err, parser.ErrEmptyCode)
+	}
+	if _, err := (&parser.Program{}).Decode(buf, opts); !errors.Is(err, parser.ErrEmptyCode) {
+		t.Fatalf("Unexpected decode error: %v, expected %s.", err, parser.ErrEmptyCode)
+	}
+}
+
+func TestWarningOptionsDisable(t *testing.T) {
+	var opts parser.WarningOptions
+	opts.Disable(" W001, ,W004,")
+	if len(opts.Disabled) != 2 || !opts.Disabled[parser.WarnRegister] || !opts.Disabled[parser.WarnHeaderMagic] {
+		t.Fatalf("Unexpected disabled warnings: %v.", opts.Disabled)
+	}
+}
@@ -0,0 +1,38 @@
+package asm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"go.creack.net/corewar/asm/parser"
+)
+
+// ErrFormatMismatch is returned when the formatted source doesn't assemble
+// to the same bytes as the original.
+var ErrFormatMismatch = errors.New("formatted source assembles differently")
+
+// Format returns the canonical form of the given source, see parser.Format.
+// Both the original and the formatted sources are assembled and
+// an error is returned unless they are byte-identical.
+func Format(inputName, inputData string) ([]byte, error) {
+	// Warnings don't matter here, only that both versions assemble the same.
+	buf, pr, err := Compile(inputName, inputData, parser.WarningOptions{})
+	if err != nil {
+		return nil, err
+	}
+	out := parser.Format(pr.Nodes)
+
+	buf2, _, err := Compile(inputName, out, parser.WarningOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFormatMismatch, err)
+	}
+	if !bytes.Equal(buf, buf2) {
+		i := 0
+		for i < len(buf) && i < len(buf2) && buf[i] == buf2[i] {
+			i++
+		}
+		return nil, fmt.Errorf("%w: first difference at byte %d", ErrFormatMismatch, i)
+	}
+	return []byte(out), nil
+}
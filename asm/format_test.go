@@ -0,0 +1,99 @@
+package asm
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.creack.net/corewar/asm/parser"
+)
+
+func TestFormat(t *testing.T) {
+	const src = `.name    "fmt"
+.comment "messy"
+start: sti r1,%:live,%1   # Store.
+  ld %0,r2 ; Carry.
+live:live %1
+	zjmp %:live     # Loop.
+`
+	const want = `.name "fmt"
+.comment "messy"
+
+start:
+	sti     r1, %:live, %1 # Store.
+	ld      %0, r2         ; Carry.
+
+live:
+	live    %1
+	zjmp    %:live # Loop.
+`
+	out, err := Format("t.s", src)
+	if err != nil {
+		t.Fatalf("Failed to format: %s.", err)
+	}
+	if string(out) != want {
+		t.Fatalf("Unexpected format:\n%s\nexpected:\n%s", out, want)
+	}
+
+	// The canonical form is stable.
+	out, err = Format("t.s", want)
+	if err != nil {
+		t.Fatalf("Failed to format the canonical form: %s.", err)
+	}
+	if string(out) != want {
+		t.Fatalf("Unexpected format of the canonical form:\n%s\nexpected:\n%s", out, want)
+	}
+}
+
+func TestFormatInvalid(t *testing.T) {
+	_, err := Format("t.s", header+"\tfoo\n")
+	var list parser.ErrorList
+	if !errors.As(err, &list) || errors.Is(err, ErrFormatMismatch) {
+		t.Fatalf("Unexpected error: %v, expected the compile errors.", err)
+	}
+}
+
+var update = flag.Bool("update", false, "Update the golden files.")
+
+func TestFormatGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.s"))
+	if err != nil {
+		t.Fatalf("Failed to list the sources: %s.", err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("No sources in testdata.")
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read the source: %s.", err)
+			}
+			out, err := Format(path, string(src))
+			if err != nil {
+				t.Fatalf("Failed to format: %s.", err)
+			}
+			golden := strings.TrimSuffix(path, ".s") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, out, 0o644); err != nil {
+					t.Fatalf("Failed to update the golden file: %s.", err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Failed to read the golden file: %s.", err)
+			}
+			if string(out) != string(want) {
+				t.Fatalf("Unexpected output:\n%s\nexpected:\n%s", out, want)
+			}
+			for _, line := range strings.Split(string(out), "\n") {
+				if strings.TrimRight(line, " \t") != line {
+					t.Fatalf("Trailing whitespace on line %q.", line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package parser
+
+import (
+	"strings"
+
+	"go.creack.net/corewar/op"
+)
+
+// Comment is a comment, on its own line or trailing a node on the same line.
+type Comment struct {
+	Text     string // Including the comment char.
+	Trailing bool   // After a node on the same line.
+	Position Position
+}
+
+func (c Comment) String() string {
+	return "<" + c.Text + ">"
+}
+
+func (c *Comment) PrettyPrint(nodes []Node) string {
+	// Trailing comments are appended to the line of the node before, see Format.
+	if c.Trailing {
+		return c.Text
+	}
+
+	// A comment right before a label, possibly with other comments, goes with it:
+	// not indented, separated from what is before instead of the label.
+	i := indexNode(nodes, c)
+	for _, n := range nodes[i+1:] {
+		if cc, ok := n.(*Comment); ok && !cc.Trailing {
+			continue
+		}
+		if _, ok := n.(*Label); ok {
+			if prev := prevNode(nodes, i); prev != nil && !isFullLineComment(prev) && !isLabel(prev) {
+				return "\n" + c.Text
+			}
+			return c.Text
+		}
+		break
+	}
+
+	// Otherwise, like directives, indent if we have a label at any point before us.
+	for _, n := range nodes[:i] {
+		if _, ok := n.(*Label); ok {
+			return "\t" + c.Text
+		}
+	}
+	return c.Text
+}
+
+func (c Comment) Pos() Position { return c.Position }
+
+func (c Comment) Encode(p *Program) ([]byte, error) {
+	return nil, nil
+}
+
+// parseComment adds the current comment token as a node.
+func (p *Parser) parseComment() {
+	c := &Comment{Text: p.currToken.val, Position: p.lexer.position(p.currToken)}
+	// If there is anything before on the same line, it is a trailing comment.
+	start := strings.LastIndexByte(p.input[:p.currToken.pos], '\n') + 1
+	c.Trailing = strings.TrimSpace(p.input[start:p.currToken.pos]) != ""
+	p.Nodes = append(p.Nodes, c)
+}
+
+// Format pretty prints the nodes, one per line, with the trailing
+// comments on the line of the node before them, aligned with the
+// ones of the lines around. The parameters of consecutive
+// instructions are aligned in columns as well.
+func Format(nodes []Node) string {
+	type line struct {
+		code    string
+		params  []string // Nil unless an instruction, then appended to code.
+		comment string   // Trailing comment, if any.
+	}
+	var lines []line
+	for _, n := range nodes {
+		if c, ok := n.(*Comment); ok && c.Trailing && len(lines) > 0 {
+			lines[len(lines)-1].comment = c.PrettyPrint(nodes)
+			continue
+		}
+		if ins, ok := n.(*Instruction); ok {
+			lines = append(lines, line{code: mnemonic(ins.OpCode.Name, len(ins.Params) > 0), params: ins.paramStrings()})
+			continue
+		}
+		lines = append(lines, line{code: n.PrettyPrint(nodes)})
+	}
+
+	// Align the parameters of consecutive instructions, each column
+	// as wide as its longest parameter followed by another one.
+	for i := 0; i < len(lines); {
+		j := i
+		var widths []int
+		for ; j < len(lines) && lines[j].params != nil; j++ {
+			// The last parameter of a line has no column to align after it.
+			params := lines[j].params
+			for k := range max(0, len(params)-1) {
+				if k == len(widths) {
+					widths = append(widths, 0)
+				}
+				widths[k] = max(widths[k], len(params[k])+1)
+			}
+		}
+		for k := i; k < j; k++ {
+			params := lines[k].params
+			for n, param := range params {
+				if n == len(params)-1 {
+					lines[k].code += param
+					break
+				}
+				lines[k].code += param + string(op.SeparatorChar) + strings.Repeat(" ", widths[n]-len(param))
+			}
+		}
+		i = max(j, i+1)
+	}
+
+	out := &strings.Builder{}
+	for i := 0; i < len(lines); {
+		if lines[i].comment == "" {
+			out.WriteString(lines[i].code + "\n")
+			i++
+			continue
+		}
+		// Align the comments of consecutive lines having one.
+		// Nodes printing on several lines, like labels after a blank line, start a new block.
+		j, width := i, 0
+		for j < len(lines) && lines[j].comment != "" && (j == i || !strings.Contains(lines[j].code, "\n")) {
+			width = max(width, textWidth(lines[j].code))
+			j++
+		}
+		for _, elem := range lines[i:j] {
+			out.WriteString(elem.code + strings.Repeat(" ", width-textWidth(elem.code)+1) + elem.comment + "\n")
+		}
+		i = j
+	}
+	return out.String()
+}
+
+// textWidth returns the width of the last line of the text, with 8 columns tabs.
+func textWidth(s string) int {
+	s = s[strings.LastIndexByte(s, '\n')+1:]
+	w := 0
+	for _, r := range s {
+		if r == '\t' {
+			w += 8 - w%8
+			continue
+		}
+		w++
+	}
+	return w
+}
+
+// indexNode returns the index of n in nodes.
+func indexNode(nodes []Node, n Node) int {
+	for i, elem := range nodes {
+		if elem == n {
+			return i
+		}
+	}
+	// Should never happen.
+	panic("self reference not found in nodes")
+}
+
+// prevNode returns the node before the one at index i,
+// skipping the trailing comments. Nil if none.
+func prevNode(nodes []Node, i int) Node {
+	for i--; i >= 0; i-- {
+		if c, ok := nodes[i].(*Comment); ok && c.Trailing {
+			continue
+		}
+		return nodes[i]
+	}
+	return nil
+}
+
+func isFullLineComment(n Node) bool {
+	c, ok := n.(*Comment)
+	return ok && !c.Trailing
+}
+
+func isLabel(n Node) bool {
+	_, ok := n.(*Label)
+	return ok
+}
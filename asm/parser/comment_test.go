@@ -0,0 +1,60 @@
+package parser
+
+import "testing"
+
+func TestFormatComments(t *testing.T) {
+	const src = `# Header comment.
+.name "t" # The name.
+.comment "c"
+
+# Before the label.
+start:	sti	r1, %:live, %1 # Trailing.
+	# Inside the code.
+	ld	%0, r2
+; Semicolon before a label.
+live:	live	%1
+	zjmp	%:live ; Last.
+`
+	const want = `# Header comment.
+.name "t" # The name.
+.comment "c"
+
+# Before the label.
+start:
+	sti     r1, %:live, %1 # Trailing.
+	# Inside the code.
+	ld      %0, r2
+
+; Semicolon before a label.
+live:
+	live    %1
+	zjmp    %:live ; Last.
+`
+	p := NewParser("t.s", src)
+	if err := p.Parse(); err != nil {
+		t.Fatalf("Failed to parse: %s.", err)
+	}
+	var trailing []string
+	for _, n := range p.Nodes {
+		if c, ok := n.(*Comment); ok && c.Trailing {
+			trailing = append(trailing, c.Text)
+		}
+	}
+	if len(trailing) != 3 || trailing[0] != "# The name." || trailing[2] != "; Last." {
+		t.Fatalf("Unexpected trailing comments: %q.", trailing)
+	}
+
+	got := Format(p.Nodes)
+	if got != want {
+		t.Fatalf("Unexpected format:\n%s\nexpected:\n%s", got, want)
+	}
+
+	// Formatting again doesn't change anything.
+	p = NewParser("t.s", got)
+	if err := p.Parse(); err != nil {
+		t.Fatalf("Failed to parse the formatted source: %s.", err)
+	}
+	if again := Format(p.Nodes); again != want {
+		t.Fatalf("Unexpected format of the formatted source:\n%s\nexpected:\n%s", again, want)
+	}
+}
@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Severity of a diagnostic.
+type Severity int
+
+// Available severities.
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// Warning codes.
+const (
+	WarnRegister    = "W001" // Register number out of range.
+	WarnCodeExtend  = "W002" // .code without .extend.
+	WarnHeaderSize  = "W003" // Program size from the header doesn't match the code.
+	WarnHeaderMagic = "W004" // Invalid magic number in the header.
+	WarnEmptyCode   = "W005" // No code after the header.
+)
+
+// ErrEmptyCode is the WarnEmptyCode warning, a program with nothing but its header.
+var ErrEmptyCode = errors.New("no code after header")
+
+// WarningOptions controls how the warnings are reported.
+// The zero value reports them all as warnings.
+type WarningOptions struct {
+	Error    bool            // Report the warnings as errors.
+	Disabled map[string]bool // Codes of the warnings to ignore.
+}
+
+// Disable ignores the given comma separated warning codes.
+func (o *WarningOptions) Disable(codes string) {
+	for code := range strings.SplitSeq(codes, ",") {
+		if code = strings.TrimSpace(code); code == "" {
+			continue
+		}
+		if o.Disabled == nil {
+			o.Disabled = map[string]bool{}
+		}
+		o.Disabled[code] = true
+	}
+}
+
+// Diagnostic is an error or a warning at a given position in the source.
+type Diagnostic struct {
+	Pos      Position
+	Source   string // Source line, empty if unknown.
+	Severity Severity
+	Code     string // Warning code, empty for plain errors.
+	Err      error
+}
+
+// Error returns the diagnostic as file:line:col: message,
+// with the severity and code for warnings, e.g. file:line:col: warning: message (W001).
+func (d *Diagnostic) Error() string {
+	msg := d.Err.Error()
+	if d.Code != "" {
+		msg = fmt.Sprintf("%s: %s (%s)", d.Severity, msg, d.Code)
+	}
+	if pos := d.Pos.String(); pos != "" {
+		return pos + ": " + msg
+	}
+	return msg
+}
+
+func (d *Diagnostic) Unwrap() error { return d.Err }
+
+// Excerpt returns the source line with a caret under the column,
+// empty if the source is unknown.
+func (d *Diagnostic) Excerpt() string {
+	if d.Source == "" || !d.Pos.IsValid() {
+		return ""
+	}
+	// Keep the tabs so the caret lines up with the source.
+	caret := &strings.Builder{}
+	for i := 0; i < d.Pos.Column-1 && i < len(d.Source); i++ {
+		if d.Source[i] == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+	}
+	caret.WriteByte('^')
+	return d.Source + "\n" + caret.String() + "\n"
+}
+
+// ErrorList is a list of diagnostics.
+type ErrorList []*Diagnostic
+
+// Error returns the first diagnostic and how many more there are.
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	case 2:
+		return fmt.Sprintf("%s (and 1 more error)", l[0])
+	default:
+		return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+	}
+}
+
+// Err returns the list as an error, nil if empty.
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// Add adds the error to the list, merging it if it is a list itself.
+func (l *ErrorList) Add(err error) {
+	var list ErrorList
+	var d *Diagnostic
+	switch {
+	case err == nil:
+	case errors.As(err, &list):
+		*l = append(*l, list...)
+	case errors.As(err, &d):
+		*l = append(*l, d)
+	default:
+		*l = append(*l, &Diagnostic{Err: err})
+	}
+}
+
+// Sort sorts the list by position.
+func (l ErrorList) Sort() {
+	slices.SortStableFunc(l, func(a, b *Diagnostic) int {
+		if c := cmp.Compare(a.Pos.File, b.Pos.File); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Pos.Line, b.Pos.Line); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Pos.Column, b.Pos.Column)
+	})
+}
+
+// ErrorAt returns the error as a diagnostic at the given position.
+// If the position is unknown, e.g. for decoded programs, the error is returned as is.
+func (p *Parser) ErrorAt(pos Position, err error) error {
+	if !pos.IsValid() {
+		return err
+	}
+	return p.newDiagnostic(pos, err)
+}
+
+// newDiagnostic returns the error as a diagnostic with the source line.
+func (p *Parser) newDiagnostic(pos Position, err error) *Diagnostic {
+	d := &Diagnostic{Pos: pos, Err: err}
+	if !pos.IsValid() {
+		return d
+	}
+	if lines := strings.Split(p.input, "\n"); pos.Line <= len(lines) {
+		d.Source = strings.TrimRight(lines[pos.Line-1], "\r")
+	}
+	return d
+}
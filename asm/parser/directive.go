@@ -2,7 +2,6 @@ package parser
 
 import (
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 
@@ -10,8 +9,9 @@ import (
 )
 
 type Directive struct {
-	Name  string
-	Value string
+	Name     string
+	Value    string
+	Position Position // Where the directive is, unset when decoded.
 }
 
 func (d Directive) String() string {
@@ -25,7 +25,7 @@ func isLastRelevantNode(nodes []Node, n Node) bool {
 				switch elem.(type) {
 				case *Label:
 				case *Directive:
-				// TODO: Add *Comment if we end up adding it.
+				case *Comment:
 				default:
 					return false
 				}
@@ -40,6 +40,11 @@ func isLastRelevantNode(nodes []Node, n Node) bool {
 func (d *Directive) PrettyPrint(nodes []Node) string {
 	out := string(op.DirectiveChar) + d.Name
 
+	// The data is indented like the instructions it stands for.
+	if out == op.CodeCmdString {
+		return mnemonic(out, d.Value != "") + d.Value
+	}
+
 	// If we have a label at any point before us,
 	// indent the directive, unless we are the last node.
 	for _, n := range nodes {
@@ -56,15 +61,13 @@ func (d *Directive) PrettyPrint(nodes []Node) string {
 		}
 	}
 	if d.Value != "" {
-		if string(op.DirectiveChar)+d.Name == op.CodeCmdString {
-			out += " " + d.Value
-		} else {
-			out += " \"" + d.Value + "\""
-		}
+		out += " \"" + d.Value + "\""
 	}
 	return out
 }
 
+func (d Directive) Pos() Position { return d.Position }
+
 func (d Directive) Encode(p *Program) ([]byte, error) {
 	startIdx := p.idx
 
@@ -77,10 +80,9 @@ func (d Directive) Encode(p *Program) ([]byte, error) {
 		return nil, nil
 	}
 	if !p.extendModeEnabled {
-		if p.strict {
-			return nil, fmt.Errorf(".extend must be set to use .code directive")
+		if err := p.Warn(d.Position, WarnCodeExtend, fmt.Errorf(".extend must be set to use .code directive")); err != nil {
+			return nil, err
 		}
-		log.Printf("Warning: .extend must be set to use .code directive")
 	}
 
 	// Parse the raw code as hex.
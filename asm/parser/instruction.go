@@ -12,6 +12,8 @@ type Instruction struct {
 	OpCode op.OpCode    // OpCode reference.
 	Params []*Parameter // Parameters.
 	Size   int          // In bytes, only set when decoding.
+
+	Position Position // Where the instruction is, unset when decoded.
 }
 
 func (ins *Instruction) ParamsDecoding(b byte) error {
@@ -60,21 +62,36 @@ func (ins Instruction) ParamsEncoding() byte {
 	return out
 }
 
-func (ins Instruction) PrettyPrint(_ []Node) string {
-	out := "\t" + ins.OpCode.Name
+// paramsColumn is the column where the parameters start, after the indented mnemonic.
+const paramsColumn = 16
+
+// mnemonic returns the indented name of an instruction or directive,
+// padded up to the parameters column unless there are none.
+func mnemonic(name string, hasParams bool) string {
+	out := "\t" + name
+	if !hasParams {
+		return out
+	}
+	return out + strings.Repeat(" ", max(1, paramsColumn-textWidth(out)))
+}
+
+func (ins Instruction) paramStrings() []string {
 	paramStrs := make([]string, 0, len(ins.Params))
 	for _, param := range ins.Params {
 		paramStrs = append(paramStrs, param.String())
 	}
-	return fmt.Sprintf("%- 8s %s", out, strings.Join(paramStrs, string(op.SeparatorChar)+" "))
+	return paramStrs
+}
+
+// PrettyPrint returns the instruction on its own, see Format
+// for the parameters aligned with the lines around.
+func (ins Instruction) PrettyPrint(_ []Node) string {
+	return mnemonic(ins.OpCode.Name, len(ins.Params) > 0) + strings.Join(ins.paramStrings(), string(op.SeparatorChar)+" ")
 }
 
 func (ins Instruction) String() string {
 	out := "<" + ins.OpCode.Name
-	paramStrs := make([]string, 0, len(ins.Params))
-	for _, param := range ins.Params {
-		paramStrs = append(paramStrs, param.String())
-	}
+	paramStrs := ins.paramStrings()
 	if len(paramStrs) == 0 {
 		return out + ">"
 	}
@@ -102,6 +119,8 @@ func (ins Instruction) ValidateParameters() error {
 	return nil
 }
 
+func (ins Instruction) Pos() Position { return ins.Position }
+
 func (ins Instruction) Encode(p *Program) ([]byte, error) {
 	// Has labels are indexed from the instruction start
 	// keep track of it.
@@ -131,7 +150,7 @@ func (ins Instruction) Encode(p *Program) ([]byte, error) {
 			} else {
 				// If we don't know the label while having
 				// the labels index, error out.
-				return nil, fmt.Errorf("unknown label %q", param.RawValue)
+				return nil, p.ErrorAt(param.Position, fmt.Errorf("unknown label %q", param.RawValue))
 			}
 		}
 		for i, elem := range param.Modifiers {
@@ -145,14 +164,24 @@ func (ins Instruction) Encode(p *Program) ([]byte, error) {
 			} else {
 				// If we don't know the label while having
 				// the labels index, error out.
-				return nil, fmt.Errorf("unknown label %q", elem)
+				return nil, p.ErrorAt(param.Position, fmt.Errorf("unknown label %q", elem.raw))
+			}
+		}
+
+		// NOTE: Registers only go from 1 to op.RegisterCount (16),
+		// some champions use others anyway, let it go unless warnings are errors.
+		if param.Typ == op.TReg {
+			if n, err := param.value(); err == nil && (n < 1 || n > op.RegisterCount) {
+				if err := p.Warn(param.Position, WarnRegister, fmt.Errorf("invalid register number %d for parameter %s", n, param)); err != nil {
+					return nil, err
+				}
 			}
 		}
 
 		// Encode the paramter and advance the index.
-		n, err := param.Encode(p.buf[p.idx:], ins.OpCode.ParamMode, p.strict)
+		n, err := param.Encode(p.buf[p.idx:], ins.OpCode.ParamMode)
 		if err != nil {
-			return nil, fmt.Errorf("failed to encode parameter %s: %w", param, err)
+			return nil, p.ErrorAt(param.Position, fmt.Errorf("failed to encode parameter %s: %w", param, err))
 		}
 		p.idx += n
 	}
@@ -199,7 +228,11 @@ func DecodeNextInstruction(buf []byte) (*Instruction, int, error) {
 	}
 
 	for _, elem := range ins.Params {
-		if idx >= len(buf) {
+		size := elem.Typ.Size()
+		if elem.Typ != op.TReg && ins.OpCode.ParamMode == op.ParamModeIndex {
+			size = op.IndirectSize
+		}
+		if idx+size > len(buf) {
 			return nil, idx, fmt.Errorf("invalid instruction, missing parameter data")
 		}
 		// Registers are always 1 byte.
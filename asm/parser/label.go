@@ -3,25 +3,22 @@ package parser
 import "go.creack.net/corewar/op"
 
 type Label struct {
-	Name string
+	Name     string
+	Position Position // Where the label is defined.
 }
 
 func (l *Label) PrettyPrint(nodes []Node) string {
-	// Unless we are immediately after a label, prefix with a newline.
-	var prev Node
-	for _, n := range nodes {
-		if l1, ok := n.(*Label); ok && l1 == l {
-			if _, ok := prev.(*Label); ok {
-				return l.Name + string(op.LabelChar)
-			}
-			return "\n" + l.Name + string(op.LabelChar)
-		}
-		prev = n
+	// Unless we are immediately after a label or a comment
+	// on its own line (which is then ours), prefix with a newline.
+	prev := prevNode(nodes, indexNode(nodes, l))
+	if isLabel(prev) || isFullLineComment(prev) {
+		return l.Name + string(op.LabelChar)
 	}
-	// Should never happen.
-	panic("self reference not found in nodes")
+	return "\n" + l.Name + string(op.LabelChar)
 }
 
+func (l Label) Pos() Position { return l.Position }
+
 func (l Label) Encode(p *Program) ([]byte, error) {
 	p.labels[l.Name] = p.idx
 	return nil, nil
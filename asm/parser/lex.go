@@ -76,6 +76,7 @@ type item struct {
 	pos  Pos      // The start position, in bytes, of this item in the input string.
 	val  string   // The value of this item.
 	line int      // The line number at the start of this item.
+	col  int      // The column, in bytes, at the start of this item.
 }
 
 func (i item) String() string {
@@ -119,11 +120,16 @@ type lexOptions struct {
 
 // errorf returns an error token and terminates the scan by passing
 // back a nil pointer that will be the next state, terminating l.nextItem.
+// The rest of the line is skipped so the next call resumes with the next line.
 func (l *lexer) errorf(format string, args ...any) stateFn {
-	l.item = item{itemError, l.start, fmt.Sprintf(format, args...), l.startLine}
-	l.start = 0
-	l.pos = 0
-	l.input = l.input[:0]
+	l.item = item{itemError, l.start, fmt.Sprintf(format, args...), l.startLine, l.column(l.start)}
+	if i := strings.IndexByte(l.input[l.pos:], '\n'); i >= 0 {
+		l.pos += Pos(i)
+	} else {
+		l.pos = Pos(len(l.input))
+	}
+	l.start = l.pos
+	l.startLine = l.line
 	return nil
 }
 
@@ -163,12 +169,22 @@ func (l *lexer) backup() {
 // thisItem returns the item at the current input point with the specified type
 // and advances the input.
 func (l *lexer) thisItem(t itemType) item {
-	i := item{t, l.start, l.input[l.start:l.pos], l.startLine}
+	i := item{t, l.start, l.input[l.start:l.pos], l.startLine, l.column(l.start)}
 	l.start = l.pos
 	l.startLine = l.line
 	return i
 }
 
+// column returns the column, starting at 1, of the given position.
+func (l *lexer) column(pos Pos) int {
+	return int(pos) - strings.LastIndexByte(l.input[:pos], '\n')
+}
+
+// position returns the source position of the item.
+func (l *lexer) position(i item) Position {
+	return Position{File: l.name, Line: i.line, Column: i.col}
+}
+
 // emit passes the trailing text as an item back to the parser.
 func (l *lexer) emit(t itemType) stateFn {
 	return l.emitItem(l.thisItem(t))
@@ -181,10 +197,8 @@ func (l *lexer) emitItem(i item) stateFn {
 }
 
 // ignore skips over the pending input before this point.
-// It tracks newlines in the ignored text, so use it only
-// for text that is skipped without calling l.next.
+// Newlines are already counted by l.next.
 func (l *lexer) ignore() {
-	l.line += strings.Count(l.input[l.start:l.pos], "\n")
 	l.start = l.pos
 	l.startLine = l.line
 }
@@ -360,6 +374,7 @@ func lexDirective(l *lexer) stateFn {
 	l.pos += Pos(nextSpace)
 	i := l.thisItem(itemDirective)
 	if i.val == string(op.DirectiveChar) {
+		l.start, l.startLine = i.pos, i.line
 		return l.errorf("missing directive name")
 	}
 	return l.emitItem(i)
@@ -368,7 +383,7 @@ func lexDirective(l *lexer) stateFn {
 // nextItem returns the next item from the input.
 // Called by the parser, not in the lexing goroutine.
 func (l *lexer) nextItem() item {
-	l.item = item{itemEOF, l.pos, "EOF", l.startLine}
+	l.item = item{itemEOF, l.pos, "EOF", l.startLine, l.column(l.pos)}
 	state := lexText
 	for {
 		state = state(l)
@@ -2,7 +2,6 @@ package parser
 
 import (
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 
@@ -23,6 +22,7 @@ type Parameter struct {
 	RawValue  string
 	Resolved  string
 	Modifiers []Modifier // Modifiers for the parameter.
+	Position  Position   // Where the parameter is, unset when decoded.
 }
 
 func (p Parameter) String() string {
@@ -57,18 +57,15 @@ func parseNumber(in string) (int64, error) {
 	return strconv.ParseInt(in, 10, 64)
 }
 
-// Encode the parameter in the given buffer based
-// on the paramter type and param mode (from the instruction opcode).
-// If the given buffer is nil, it will not write anything but still return
-// how many bytes would have been written.
-func (p Parameter) Encode(buf []byte, paramMode op.ParamMode, strict bool) (int, error) {
+// value returns the value of the parameter with the modifiers applied.
+// Unresolved label references are skipped, they will be known with the next pass.
+func (p Parameter) value() (int64, error) {
 	val := p.Resolved
 	if val == "" {
 		val = p.RawValue
 	}
 	// Parse the value as a number.
 	n, err := parseNumber(val)
-	p.Value = int64(n)
 	if err != nil && !strings.HasPrefix(val, string(op.LabelChar)) {
 		// If we have a label, it will error out but keep going. Will pass next time around.
 		return 0, fmt.Errorf("parse %q: %w", val, err)
@@ -103,17 +100,23 @@ func (p Parameter) Encode(buf []byte, paramMode op.ParamMode, strict bool) (int,
 			n += n1
 		}
 	}
+	return n, nil
+}
+
+// Encode the parameter in the given buffer based
+// on the paramter type and param mode (from the instruction opcode).
+// If the given buffer is nil, it will not write anything but still return
+// how many bytes would have been written.
+func (p Parameter) Encode(buf []byte, paramMode op.ParamMode) (int, error) {
+	n, err := p.value()
+	if err != nil {
+		return 0, err
+	}
 
 	// Simplest case, register.
+	// NOTE: Registers only go from 1 to op.RegisterCount (16),
+	// out of range ones are reported as warnings by Instruction.Encode.
 	if p.Typ == op.TReg {
-		// NOTE: Registers only go from 1 to op.RegisterCount (16),
-		// when in strict mode, enforce it, otherwise, let it go.
-		if n < 1 || n > op.RegisterCount {
-			if strict {
-				return 0, fmt.Errorf("invalid register number %d", n)
-			}
-			log.Printf("Warning: invalid register number %d for parameter %s", n, p)
-		}
 		if buf != nil {
 			buf[0] = byte(n)
 		}
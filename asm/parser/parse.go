@@ -10,10 +10,12 @@ import (
 type Node interface {
 	Encode(*Program) ([]byte, error)
 	PrettyPrint([]Node) string
+	Pos() Position // Where the node is in the source, unset when decoded.
 }
 
 // Parser structure
 type Parser struct {
+	input     string // Kept for the diagnostics, the lexer consumes it.
 	lexer     *lexer
 	currToken item
 	peekToken item
@@ -25,6 +27,7 @@ type Parser struct {
 // NewParser creates a new parser
 func NewParser(name, input string) *Parser {
 	p := &Parser{
+		input: input,
 		lexer: NewLexer(name, input),
 	}
 	// Preload the next token.
@@ -35,43 +38,62 @@ func NewParser(name, input string) *Parser {
 
 // GetDirective returns the last value of the given directive key.
 func (p *Parser) GetDirective(name string) string {
+	if d := p.LookupDirective(name); d != nil {
+		return d.Value
+	}
+	return ""
+}
+
+// LookupDirective returns the last directive with the given key, nil if none.
+func (p *Parser) LookupDirective(name string) *Directive {
 	name = strings.TrimPrefix(name, string(op.DirectiveChar))
-	var out string
+	var out *Directive
 	for _, elem := range p.Nodes {
 		if d, ok := elem.(*Directive); ok && d.Name == name {
-			out = d.Value
+			out = d
 		}
 	}
 	return out
 }
 
+// errorf returns a diagnostic at the given token.
+func (p *Parser) errorf(tok item, format string, args ...any) error {
+	return p.ErrorAt(p.lexer.position(tok), fmt.Errorf(format, args...))
+}
+
 func (p *Parser) parseDirective() error {
 	p.curInstruction = nil // If we reach a directive, we are not in an instruction anymore.
 
 	directiveName := strings.TrimPrefix(p.currToken.val, string(op.DirectiveChar))
+	pos := p.lexer.position(p.currToken)
 	p.nextToken()
 	if p.currToken.typ == itemError {
-		return fmt.Errorf("unexpected token %s", p.currToken)
+		return p.errorf(p.currToken, "%s", p.currToken.val)
 	}
 
-	d := &Directive{Name: directiveName}
+	d := &Directive{Name: directiveName, Position: pos}
 
 	// If we have a raw string, use it as value, if we have EOL, the value is empty.
 	if p.currToken.typ.isEOL() || p.currToken.typ == itemRawString {
-		d.Value = strings.Trim(p.currToken.val, "\"")
+		if p.currToken.typ == itemRawString {
+			d.Value = strings.Trim(p.currToken.val, "\"")
+		}
 		p.Nodes = append(p.Nodes, d)
+		if p.currToken.typ == itemComment {
+			p.parseComment()
+		}
 		return nil
 	}
 
 	// The directive value must be a string or a number, as we accept hexadecimal without prefix, it can be an identifier.
 	if p.currToken.typ != itemNumber && p.currToken.typ != itemIdentifier {
-		return fmt.Errorf("expected number or identifier, got %s for %q", p.currToken, directiveName)
+		return p.errorf(p.currToken, "expected number or identifier, got %s for %q", p.currToken, directiveName)
 	}
 
 	var values []string
 	for !p.currToken.typ.isEOL() {
 		if p.currToken.typ == itemError {
-			return fmt.Errorf("unexpected token %s", p.currToken)
+			return p.errorf(p.currToken, "%s", p.currToken.val)
 		}
 		values = append(values, p.currToken.val)
 		p.nextToken()
@@ -79,23 +101,25 @@ func (p *Parser) parseDirective() error {
 
 	d.Value = strings.Join(values, " ")
 	p.Nodes = append(p.Nodes, d)
+	if p.currToken.typ == itemComment {
+		p.parseComment()
+	}
 	return nil
 }
 
 func (p *Parser) parseLabel() error {
 	for _, elem := range p.Nodes {
 		if l, ok := elem.(*Label); ok && l.Name == p.currToken.val {
-			// TODO: Embed the pos to report where was the other label.
-			return fmt.Errorf("duplicate label %q", p.currToken.val)
+			return p.errorf(p.currToken, "duplicate label %q, first defined at %s", p.currToken.val, l.Position)
 		}
 	}
-	p.Nodes = append(p.Nodes, &Label{Name: p.currToken.val})
+	p.Nodes = append(p.Nodes, &Label{Name: p.currToken.val, Position: p.lexer.position(p.currToken)})
 	return nil
 }
 
 func (p *Parser) parseIdentifier() error {
 	if p.curInstruction == nil {
-		ins := Instruction{}
+		ins := Instruction{Position: p.lexer.position(p.currToken)}
 		p.Nodes = append(p.Nodes, &ins)
 		p.curInstruction = &ins
 	}
@@ -103,7 +127,7 @@ func (p *Parser) parseIdentifier() error {
 	// If we don't have the instruction name yet, we need it.
 	if p.curInstruction.OpCode.Name == "" {
 		if p.currToken.typ != itemIdentifier {
-			return fmt.Errorf("expected instruction name, got %s", p.currToken)
+			return p.errorf(p.currToken, "expected instruction name, got %s", p.currToken)
 		}
 		for _, ins := range op.OpCodeTable {
 			if ins.Name == p.currToken.val {
@@ -112,7 +136,7 @@ func (p *Parser) parseIdentifier() error {
 			}
 		}
 		if p.curInstruction.OpCode.Name == "" {
-			return fmt.Errorf("unknown instruction %q", p.currToken.val)
+			return p.errorf(p.currToken, "unknown instruction %q", p.currToken.val)
 		}
 	}
 	return p.parseInstructionParameters()
@@ -124,7 +148,10 @@ func (p *Parser) parseInstructionParameters() error {
 		p.nextToken()
 
 		if p.currToken.typ == itemError {
-			return fmt.Errorf("unexpected token %s", p.currToken)
+			return p.errorf(p.currToken, "%s", p.currToken.val)
+		}
+		if !param.Position.IsValid() && p.currToken.typ != itemComa && !p.currToken.typ.isEOL() {
+			param.Position = p.lexer.position(p.currToken)
 		}
 
 		// If we have an identifier, it can be a register if it starts with 'r'
@@ -134,7 +161,7 @@ func (p *Parser) parseInstructionParameters() error {
 				param.Typ = op.TReg
 				param.RawValue = p.currToken.val[1:]
 			} else {
-				return fmt.Errorf("unexpected identifier %q in %q", p.currToken, p.curInstruction)
+				return p.errorf(p.currToken, "unexpected identifier %q in %q", p.currToken, p.curInstruction)
 			}
 			continue
 		}
@@ -163,7 +190,7 @@ func (p *Parser) parseInstructionParameters() error {
 		// Direct value.
 		if p.currToken.typ == itemPercent {
 			if param.Typ != 0 {
-				return fmt.Errorf("unexpected %s in %q", p.currToken, p.curInstruction)
+				return p.errorf(p.currToken, "unexpected %s in %q", p.currToken, p.curInstruction)
 			}
 			param.Typ = op.TDir
 			p.nextToken()
@@ -174,7 +201,7 @@ func (p *Parser) parseInstructionParameters() error {
 			} else if p.currToken.typ == itemNumber {
 				param.RawValue = p.currToken.val
 			} else {
-				return fmt.Errorf("expected number or label reference for direct value, got %s", p.currToken)
+				return p.errorf(p.currToken, "expected number or label reference for direct value, got %s", p.currToken)
 			}
 			continue
 		}
@@ -183,7 +210,7 @@ func (p *Parser) parseInstructionParameters() error {
 			p.curInstruction.Params = append(p.curInstruction.Params, param)
 			param = &Parameter{}
 			if p.peekToken.typ.isEOL() {
-				return fmt.Errorf("unexpected comma at the end of instruction %s", p.curInstruction)
+				return p.errorf(p.currToken, "unexpected comma at the end of instruction %s", p.curInstruction)
 			}
 			continue
 		}
@@ -196,16 +223,19 @@ func (p *Parser) parseInstructionParameters() error {
 			}
 
 			if err := p.curInstruction.ValidateParameters(); err != nil {
-				return fmt.Errorf("invalid instruction %s: %w", p.curInstruction, err)
+				return p.ErrorAt(p.curInstruction.Position, fmt.Errorf("invalid instruction %s: %w", p.curInstruction, err))
 			}
 
 			// Mark curInstruction as nil, the next parseIdentifier call will set it
 			// to the new one.
 			p.curInstruction = nil
+			if p.currToken.typ == itemComment {
+				p.parseComment()
+			}
 			break
 		}
 
-		return fmt.Errorf("unexpected token %s", p.currToken)
+		return p.errorf(p.currToken, "unexpected token %s", p.currToken)
 	}
 	return nil
 }
@@ -216,7 +246,10 @@ func (p *Parser) nextToken() {
 	p.peekToken = p.lexer.nextItem()
 }
 
+// Parse parses the whole input. On error, the rest of the line is
+// skipped and parsing goes on, all the errors are returned as an ErrorList.
 func (p *Parser) Parse() error {
+	var errs ErrorList
 	for {
 		p.nextToken()
 		item := p.currToken
@@ -224,7 +257,9 @@ func (p *Parser) Parse() error {
 			break
 		}
 		if item.typ == itemError {
-			return fmt.Errorf("[%d:%d]: %s", item.line, item.pos, item.val)
+			errs.Add(p.errorf(item, "%s", item.val))
+			p.skipLine()
+			continue
 		}
 
 		var err error
@@ -234,18 +269,31 @@ func (p *Parser) Parse() error {
 		case itemDirective:
 			err = p.parseDirective()
 		case itemComment:
-			continue
+			p.parseComment()
 		case itemIdentifier:
 			err = p.parseIdentifier()
 		case itemLabel:
 			err = p.parseLabel()
 		default:
-			return fmt.Errorf("unexpected item %s", item)
+			err = p.errorf(item, "unexpected item %s", item)
 		}
 		if err != nil {
-			return fmt.Errorf("error parsing item: %s", err)
+			errs.Add(err)
+			p.skipLine()
 		}
 	}
 
-	return nil
+	return errs.Err()
+}
+
+// skipLine drops the instruction being parsed, if any,
+// and skips the tokens up to the end of the line.
+func (p *Parser) skipLine() {
+	if p.curInstruction != nil && len(p.Nodes) > 0 && p.Nodes[len(p.Nodes)-1] == Node(p.curInstruction) {
+		p.Nodes = p.Nodes[:len(p.Nodes)-1]
+	}
+	p.curInstruction = nil
+	for !p.currToken.typ.isEOL() {
+		p.nextToken()
+	}
 }
@@ -0,0 +1,21 @@
+package parser
+
+import "fmt"
+
+// Position is a location in the source.
+type Position struct {
+	File   string
+	Line   int // Starting at 1.
+	Column int // Starting at 1, in bytes.
+}
+
+// IsValid reports whether the position is set.
+// Decoded nodes don't have one.
+func (p Position) IsValid() bool { return p.Line > 0 }
+
+func (p Position) String() string {
+	if !p.IsValid() {
+		return p.File
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
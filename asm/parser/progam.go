@@ -3,7 +3,7 @@ package parser
 import (
 	"errors"
 	"fmt"
-	"log"
+	"slices"
 	"strings"
 
 	"go.creack.net/corewar/op"
@@ -17,12 +17,14 @@ type Program struct {
 	labels           map[string]int
 	hasLabelIndex    bool
 	hasMissingLabels bool
+	offsets          []int // Offset of each node in the code.
 
 	extendModeEnabled bool
-	strict            bool
+	warnOpts          WarningOptions
+	warnings          ErrorList
 }
 
-func NewProgram(p *Parser, strict bool) *Program {
+func NewProgram(p *Parser, warnOpts WarningOptions) *Program {
 	return &Program{
 		Parser: p,
 
@@ -32,14 +34,47 @@ func NewProgram(p *Parser, strict bool) *Program {
 		hasLabelIndex:     false,
 		hasMissingLabels:  false,
 		extendModeEnabled: false,
-		strict:            strict,
+		warnOpts:          warnOpts,
 	}
 }
 
+// Warnings returns the warnings reported while encoding or decoding.
+func (p *Program) Warnings() ErrorList {
+	return p.warnings
+}
+
+// AddWarnings adds warnings reported elsewhere, e.g. when decoding
+// the binary the program got found from.
+func (p *Program) AddWarnings(warnings ErrorList) {
+	p.warnings = append(p.warnings, warnings...)
+}
+
+// Warn reports a warning at the given position. Returns it as an error
+// if the warnings are errors, nil otherwise.
+func (p *Program) Warn(pos Position, code string, err error) error {
+	if p.warnOpts.Disabled[code] {
+		return nil
+	}
+	d := p.newDiagnostic(pos, err)
+	d.Code = code
+	if p.warnOpts.Error {
+		return d
+	}
+	d.Severity = SeverityWarning
+	p.warnings = append(p.warnings, d)
+	return nil
+}
+
 func (p Program) Size() int {
 	return p.idx
 }
 
+// Offsets returns the offset in the code of each node, in the same
+// order as the nodes. Only set after Encode.
+func (p *Program) Offsets() []int {
+	return p.offsets
+}
+
 func (p *Program) encode() error {
 	// If we have labels, it means we already encoded once and have the labels index.
 	// Error out if we encounter a label that we don't know
@@ -48,34 +83,51 @@ func (p *Program) encode() error {
 		p.labels = map[string]int{}
 	}
 	p.idx = 0
+	p.offsets = p.offsets[:0]
+	var errs ErrorList
 	for _, n := range p.Parser.Nodes {
+		p.offsets = append(p.offsets, p.idx)
 		if _, err := n.Encode(p); err != nil {
-			return fmt.Errorf("failed to encode instruction %s: %w", n, err)
+			// Decoded nodes have no position, stop at the first error.
+			if !n.Pos().IsValid() {
+				return fmt.Errorf("failed to encode instruction %s: %w", n, err)
+			}
+			var d *Diagnostic
+			if !errors.As(err, &d) {
+				err = p.ErrorAt(n.Pos(), fmt.Errorf("failed to encode %s: %w", n, err))
+			}
+			errs.Add(err)
 		}
 	}
 
-	return nil
+	return errs.Err()
 }
 
+// Encode encodes the nodes. Errors are returned as an ErrorList
+// with all of them, unless the nodes were decoded.
 func (p *Program) Encode() ([]byte, error) {
-	if err := p.encode(); err != nil {
-		return nil, fmt.Errorf("failed to first encode program: %w", err)
-	}
+	warnings := len(p.warnings)
+	err := p.encode()
 
 	// If we don't have any missing labels, we don't need to re-encode.
 	if !p.hasMissingLabels {
+		if err != nil {
+			return nil, err
+		}
 		return p.buf[:p.idx], nil
 	}
 
 	// If we have missing labels, we need to re-encode the program.
+	// The errors and warnings of the first pass show up again, along with the unknown labels.
+	p.warnings = p.warnings[:warnings]
 	if err := p.encode(); err != nil {
-		return nil, fmt.Errorf("failed to re-encode program: %w", err)
+		return nil, err
 	}
 
 	return p.buf[:p.idx], nil
 }
 
-func (p *Program) Decode(data []byte, strict bool) (*Parser, error) {
+func (p *Program) Decode(data []byte, warnOpts WarningOptions) (*Parser, error) {
 	if len(data) > op.MemSize {
 		return nil, fmt.Errorf("program size %d exceeds memory size %d", len(data), op.MemSize)
 	}
@@ -83,29 +135,86 @@ func (p *Program) Decode(data []byte, strict bool) (*Parser, error) {
 	copy(p.buf, data)
 
 	p.Parser = &Parser{}
-	if err := p.DecodeHeader(strict); err != nil {
+	p.warnOpts = warnOpts
+	if err := p.DecodeHeader(); err != nil {
 		return nil, fmt.Errorf("failed to decode header: %w", err)
 	}
 
-	for p.idx < len(p.buf) {
-		ins, idx, err := DecodeNextInstruction(p.buf[p.idx:])
-		if err != nil {
-			if errors.Is(err, ErrInvalidOpcode) {
-				// TODO: Handle this. Likely .code directive.
-				// Look for the offset with the most valid instructions,
-				// as the raw code could contain part of the instruction.
-				p.idx++
-				continue
-			}
-			return nil, fmt.Errorf("failed to decode instruction: %w", err)
+	p.decodeCode(p.buf[p.idx:])
+	p.idx = len(p.buf)
+	return p.Parser, nil
+}
+
+// codeDirectiveBytes is how many bytes of data go in each .code directive.
+const codeDirectiveBytes = 16
+
+// decodeCode decodes the code into instructions. The bytes which are not
+// instructions, e.g. invalid opcodes or inline constants, are kept as data
+// in .code directives, preceded by .extend.
+// As data can contain what looks like the start of an instruction, the
+// resync point is the one decoding the most bytes as instructions afterwards.
+func (p *Program) decodeCode(code []byte) {
+	// Instruction at each offset, nil if the bytes there are not one.
+	instructions := make([]*Instruction, len(code))
+	for i := range code {
+		instructions[i] = decodeInstructionAt(code, i)
+	}
+
+	// From the end, the fewest data bytes needed to decode the rest of
+	// the code, and whether it is done with an instruction at each offset.
+	dataBytes := make([]int, len(code)+1)
+	useInstruction := make([]bool, len(code))
+	for i := len(code) - 1; i >= 0; i-- {
+		dataBytes[i] = 1 + dataBytes[i+1]
+		if ins := instructions[i]; ins != nil && dataBytes[i+ins.Size] <= dataBytes[i] {
+			dataBytes[i] = dataBytes[i+ins.Size]
+			useInstruction[i] = true
 		}
-		p.idx += idx
-		p.Parser.Nodes = append(p.Parser.Nodes, ins)
 	}
-	return p.Parser, nil
+
+	var data []string
+	flush := func() {
+		for chunk := range slices.Chunk(data, codeDirectiveBytes) {
+			p.Parser.Nodes = append(p.Parser.Nodes, &Directive{
+				Name:  strings.TrimPrefix(op.CodeCmdString, string(op.DirectiveChar)),
+				Value: strings.Join(chunk, " "),
+			})
+		}
+		data = data[:0]
+	}
+	if dataBytes[0] > 0 {
+		p.Parser.Nodes = append(p.Parser.Nodes, &Directive{Name: strings.TrimPrefix(op.ExtendCmdString, string(op.DirectiveChar))})
+	}
+	for i := 0; i < len(code); {
+		if !useInstruction[i] {
+			// NOTE: Lowercase, the lexer would split "3C" in two tokens.
+			data = append(data, fmt.Sprintf("%02x", code[i]))
+			i++
+			continue
+		}
+		flush()
+		p.Parser.Nodes = append(p.Parser.Nodes, instructions[i])
+		i += instructions[i].Size
+	}
+	flush()
 }
 
-func (p *Program) DecodeHeader(strict bool) error {
+// decodeInstructionAt decodes the instruction at the given offset of the code.
+// Returns nil if there is none or if it would not assemble back to the same bytes,
+// e.g. with unused bits set in the encoding byte.
+func decodeInstructionAt(code []byte, i int) *Instruction {
+	ins, size, err := DecodeNextInstruction(code[i:])
+	if err != nil {
+		return nil
+	}
+	if ins.OpCode.EncodingByte && ins.ParamsEncoding() != code[i+1] {
+		return nil
+	}
+	ins.Size = size
+	return ins
+}
+
+func (p *Program) DecodeHeader() error {
 	h := op.Header{}
 	headerSize, nameLength, commentLength := op.HeaderStructSize()
 
@@ -117,10 +226,9 @@ func (p *Program) DecodeHeader(strict bool) error {
 	h.Magic = op.Endian.Uint32(p.buf[p.idx : p.idx+4])
 	p.idx += 4
 	if h.Magic != op.CorewarExecMagic {
-		if strict {
-			return fmt.Errorf("invalid magic number: %x, expect %x", h.Magic, op.CorewarExecMagic)
+		if err := p.Warn(Position{}, WarnHeaderMagic, fmt.Errorf("invalid magic number: %x, expect %x", h.Magic, op.CorewarExecMagic)); err != nil {
+			return err
 		}
-		log.Printf("Warning: invalid magic number: %x, expect %x", h.Magic, op.CorewarExecMagic)
 	}
 	copy(h.ProgName[:], p.buf[p.idx:p.idx+nameLength])
 	p.idx += nameLength
@@ -132,14 +240,15 @@ func (p *Program) DecodeHeader(strict bool) error {
 	p.idx += commentLength
 
 	if p.idx >= len(p.buf) {
-		return fmt.Errorf("no code after header")
+		if err := p.Warn(Position{}, WarnEmptyCode, ErrEmptyCode); err != nil {
+			return err
+		}
 	}
 
 	if p.idx+int(h.ProgSize) != len(p.buf) {
-		if strict {
-			return fmt.Errorf("program size from header doesn't match actual code size, header: %d, actual: %d", h.ProgSize, len(p.buf)-p.idx)
+		if err := p.Warn(Position{}, WarnHeaderSize, fmt.Errorf("program size from header doesn't match actual code size, header: %d, actual: %d", h.ProgSize, len(p.buf)-p.idx)); err != nil {
+			return err
 		}
-		log.Printf("Warning: program size from header doesn't match actual code size, header: %d, actual: %d\n", h.ProgSize, len(p.buf)-p.idx)
 	}
 
 	p.Parser.Nodes = append(p.Parser.Nodes, &Directive{
@@ -0,0 +1,105 @@
+package asm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"go.creack.net/corewar/asm/parser"
+)
+
+// SourceMap maps the offsets of the compiled code back to the source.
+// Stored as JSON next to the .cor file, with a .map suffix.
+type SourceMap struct {
+	File    string           `json:"file"`
+	Entries []SourceMapEntry `json:"entries"` // Sorted by offset.
+}
+
+// SourceMapEntry is the source of an instruction or raw code.
+type SourceMapEntry struct {
+	Offset int    `json:"offset"` // From the start of the code, header excluded.
+	Size   int    `json:"size"`   // In bytes.
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Label  string `json:"label,omitempty"` // Closest label before the entry.
+	Text   string `json:"text"`            // Source line, trimmed.
+}
+
+// NewSourceMap creates the source map of the given encoded program.
+func NewSourceMap(inputName, inputData string, pr *parser.Program) *SourceMap {
+	lines := strings.Split(inputData, "\n")
+	offsets := pr.Offsets()
+
+	m := &SourceMap{File: inputName}
+	var label string
+	for i, n := range pr.Nodes {
+		if i >= len(offsets) {
+			break
+		}
+		var pos parser.Position
+		switch n := n.(type) {
+		case *parser.Label:
+			label = n.Name
+			continue
+		case *parser.Instruction:
+			pos = n.Position
+		case *parser.Directive:
+			pos = n.Position
+		}
+		end := pr.Size()
+		if i+1 < len(offsets) {
+			end = offsets[i+1]
+		}
+		if end <= offsets[i] || !pos.IsValid() {
+			continue
+		}
+		e := SourceMapEntry{
+			Offset: offsets[i],
+			Size:   end - offsets[i],
+			Line:   pos.Line,
+			Column: pos.Column,
+			Label:  label,
+		}
+		if pos.Line <= len(lines) {
+			e.Text = strings.TrimSpace(lines[pos.Line-1])
+		}
+		m.Entries = append(m.Entries, e)
+	}
+	return m
+}
+
+// Lookup returns the entry containing the given code offset.
+func (m *SourceMap) Lookup(offset int) (SourceMapEntry, bool) {
+	i := sort.Search(len(m.Entries), func(i int) bool { return m.Entries[i].Offset+m.Entries[i].Size > offset })
+	if i == len(m.Entries) || m.Entries[i].Offset > offset {
+		return SourceMapEntry{}, false
+	}
+	return m.Entries[i], true
+}
+
+// WriteSourceMap writes the source map as JSON to the given path.
+func WriteSourceMap(path string, m *SourceMap) error {
+	buf, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode source map: %w", err)
+	}
+	if err := os.WriteFile(path, buf, 0o644); err != nil {
+		return fmt.Errorf("failed to write source map: %w", err)
+	}
+	return nil
+}
+
+// LoadSourceMap reads a source map written by WriteSourceMap.
+func LoadSourceMap(path string) (*SourceMap, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source map: %w", err)
+	}
+	var m SourceMap
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode source map: %w", err)
+	}
+	return &m, nil
+}
@@ -0,0 +1,56 @@
+package asm
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.creack.net/corewar/asm/parser"
+)
+
+func TestSourceMap(t *testing.T) {
+	const src = `.name "t"
+.comment "c"
+
+start:	sti	r1, %:live, %1
+	ld	%0, r2
+live:	live	%1
+	zjmp	%:live
+`
+	_, pr, err := Compile("t.s", src, parser.WarningOptions{})
+	if err != nil {
+		t.Fatalf("Failed to compile: %s.", err)
+	}
+	m := NewSourceMap("t.s", src, pr)
+	want := &SourceMap{File: "t.s", Entries: []SourceMapEntry{
+		{Offset: 0, Size: 7, Line: 4, Column: 8, Label: "start", Text: "start:\tsti\tr1, %:live, %1"},
+		{Offset: 7, Size: 7, Line: 5, Column: 2, Label: "start", Text: "ld\t%0, r2"},
+		{Offset: 14, Size: 5, Line: 6, Column: 7, Label: "live", Text: "live:\tlive\t%1"},
+		{Offset: 19, Size: 3, Line: 7, Column: 2, Label: "live", Text: "zjmp\t%:live"},
+	}}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("Unexpected source map:\n%+v\nexpected:\n%+v", m, want)
+	}
+
+	for _, tt := range []struct {
+		offset int
+		line   int // 0 when not found.
+	}{{0, 4}, {6, 4}, {7, 5}, {16, 6}, {21, 7}, {22, 0}, {-1, 0}} {
+		e, ok := m.Lookup(tt.offset)
+		if ok != (tt.line != 0) || e.Line != tt.line {
+			t.Fatalf("Lookup of offset %d found line %d (%t), expected %d.", tt.offset, e.Line, ok, tt.line)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "t.cor.map")
+	if err := WriteSourceMap(path, m); err != nil {
+		t.Fatalf("Failed to write the source map: %s.", err)
+	}
+	loaded, err := LoadSourceMap(path)
+	if err != nil {
+		t.Fatalf("Failed to load the source map: %s.", err)
+	}
+	if !reflect.DeepEqual(loaded, m) {
+		t.Fatalf("Unexpected loaded source map:\n%+v\nexpected:\n%+v", loaded, m)
+	}
+}
@@ -0,0 +1,10 @@
+.name "code"
+.comment "raw data between the instructions"
+.extend
+.code 0b ff
+	live %1
+	noop
+	st r1, 12
+l:
+.code 01 00 00
+	zjmp %:l
@@ -0,0 +1,12 @@
+.name "columns"
+.comment "parameters of all widths"
+
+# Setup.
+init:	sti r1,%:live,%1
+	and r1,%0,r1   ; Carry.
+	ldi %:init,%4,r3
+	st r3,-64
+live:	live %1
+	fork %:init # Child.
+	lldi %-12,r2,r4
+	zjmp %:live
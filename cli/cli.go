@@ -2,6 +2,8 @@
 package cli
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"slices"
@@ -11,26 +13,40 @@ import (
 	"go.creack.net/corewar/asm"
 	"go.creack.net/corewar/asm/parser"
 	"go.creack.net/corewar/disasm"
+	"go.creack.net/corewar/library"
 	"go.creack.net/corewar/op"
 	"go.creack.net/corewar/vm"
 )
 
 const MaxPlayers = 4
 
+// Options holds the parsed flags which are not about the players.
+type Options struct {
+	DumpCycle int    // Cycle after which to dump the memory and exit, -1 if not set.
+	DumpWidth int    // Bytes per line of the memory dump.
+	Verbose   int    // Verbosity bitmask, see cmd/corewar.
+	Snapshot  string // Snapshot to resume the game from.
+	Record    string // File to record the game replay to.
+	Replay    string // Replay file to play instead of the players.
+}
+
 type Player struct {
 	PathName  string
+	Champion  string // Name in the embedded library, loaded instead of a file.
 	ShortName string
 	Number    int
 	Data      []byte
 
-	Prog *parser.Program
+	Prog      *parser.Program
+	SourceMap *asm.SourceMap // Nil if unknown.
 }
 
-func parse() ([]*Player, error) {
+func parse() ([]*Player, Options, error) {
 	// Define a variable to hold the -n value temporarily
 	var number int
 
 	var players []*Player
+	opts := Options{DumpCycle: -1}
 
 	// Process arguments manually
 	args := os.Args[1:]
@@ -40,7 +56,7 @@ func parse() ([]*Player, error) {
 		if arg == "-n" && i+1 < len(args) {
 			num, err := strconv.Atoi(args[i+1])
 			if err != nil {
-				return nil, fmt.Errorf("invalid number for -n flag: %q", args[i+1])
+				return nil, opts, fmt.Errorf("invalid number for -n flag: %q", args[i+1])
 			}
 			number = num
 			i++ // Skip the value of -n
@@ -49,20 +65,94 @@ func parse() ([]*Player, error) {
 			arg = strings.TrimPrefix(arg, "-n")
 			num, err := strconv.Atoi(arg)
 			if err != nil {
-				return nil, fmt.Errorf("invalid number for -n flag: %q", arg)
+				return nil, opts, fmt.Errorf("invalid number for -n flag: %q", arg)
 			}
 			number = num
 			continue
 		}
 
+		// -dump N dumps the memory after cycle N, 32 bytes per line,
+		// -d N does the same with 64 bytes per line.
+		if (arg == "-dump" || arg == "-d") && i+1 < len(args) {
+			cycle, err := strconv.Atoi(args[i+1])
+			if err != nil || cycle < 0 {
+				return nil, opts, fmt.Errorf("invalid cycle for %s flag: %q", arg, args[i+1])
+			}
+			opts.DumpCycle = cycle
+			opts.DumpWidth = 32
+			if arg == "-d" {
+				opts.DumpWidth = 64
+			}
+			i++ // Skip the value.
+			continue
+		}
+
+		// -v N sets the verbosity bitmask.
+		if arg == "-v" && i+1 < len(args) {
+			level, err := strconv.Atoi(args[i+1])
+			if err != nil || level < 0 {
+				return nil, opts, fmt.Errorf("invalid level for -v flag: %q", args[i+1])
+			}
+			opts.Verbose = level
+			i++ // Skip the value.
+			continue
+		}
+
+		// -load file resumes the game from a snapshot, players included.
+		if arg == "-load" && i+1 < len(args) {
+			opts.Snapshot = args[i+1]
+			i++ // Skip the value.
+			continue
+		}
+
+		// -record file records the game, -replay file plays a recorded one.
+		if (arg == "-record" || arg == "-replay") && i+1 < len(args) {
+			if arg == "-record" {
+				opts.Record = args[i+1]
+			} else {
+				opts.Replay = args[i+1]
+			}
+			i++ // Skip the value.
+			continue
+		}
+
+		// -champ name loads a champion from the embedded library.
+		if arg == "-champ" && i+1 < len(args) {
+			players = append(players, &Player{PathName: args[i+1], Champion: args[i+1], Number: number})
+			number = 0 // Reset for the next player
+			i++        // Skip the value.
+			continue
+		}
+
 		// If it's not a flag, it's a player name
 		if arg[0] != '-' {
 			players = append(players, &Player{PathName: arg, Number: number})
 			number = 0 // Reset for the next player
 		}
 	}
+	if opts.Replay != "" {
+		if len(players) != 0 {
+			return nil, opts, fmt.Errorf("players can't be provided with -replay")
+		}
+		if opts.Snapshot != "" {
+			return nil, opts, fmt.Errorf("-load can't be used with -replay")
+		}
+		if opts.Record != "" {
+			return nil, opts, fmt.Errorf("-record can't be used with -replay, the game is already recorded")
+		}
+		if opts.DumpCycle >= 0 {
+			return nil, opts, fmt.Errorf("-dump can't be used with -replay")
+		}
+		return nil, opts, nil
+	}
+	if opts.Snapshot != "" {
+		if len(players) != 0 {
+			return nil, opts, fmt.Errorf("players can't be provided with -load, they are in the snapshot")
+		}
+		return nil, opts, nil
+	}
 	if len(players) == 0 {
-		return nil, fmt.Errorf("no players provided")
+		return nil, opts, fmt.Errorf("no players provided")
 	}
 
 	// Make sure we don't have a duplicate number.
@@ -74,17 +164,17 @@ func parse() ([]*Player, error) {
 	}
 	// Go over the parsed players, and remove from the available list the numbers we already have.
 	for _, p := range players {
-		if !strings.HasSuffix(p.PathName, ".s") && !strings.HasSuffix(p.PathName, ".cor") {
-			return nil, fmt.Errorf("invalid file extension for %q, must be .s or .cor", p.PathName)
+		if p.Champion == "" && !strings.HasSuffix(p.PathName, ".s") && !strings.HasSuffix(p.PathName, ".cor") {
+			return nil, opts, fmt.Errorf("invalid file extension for %q, must be .s or .cor", p.PathName)
 		}
 		if p.Number == 0 {
 			continue
 		}
 		if p.Number < 1 || p.Number > MaxPlayers {
-			return nil, fmt.Errorf("invalid player number: %d for %q, must be between 1 and %d", p.Number, p.PathName, MaxPlayers)
+			return nil, opts, fmt.Errorf("invalid player number: %d for %q, must be between 1 and %d", p.Number, p.PathName, MaxPlayers)
 		}
 		if n, ok := inputNumbers[p.Number]; ok {
-			return nil, fmt.Errorf("duplicate player number: %d, used for %q and %q", p.Number, p.PathName, n)
+			return nil, opts, fmt.Errorf("duplicate player number: %d, used for %q and %q", p.Number, p.PathName, n)
 		}
 		inputNumbers[p.Number] = p.PathName
 		numbers = slices.DeleteFunc(numbers, func(elem int) bool { return elem == p.Number })
@@ -100,31 +190,51 @@ func parse() ([]*Player, error) {
 		}
 	}
 
-	return players, nil
+	return players, opts, nil
 }
 
 func loadPlayers(players []*Player) error {
 	for _, p := range players {
-		tmp := strings.Split(p.PathName, "/")
-		p.ShortName = tmp[len(tmp)-1]
-		p.ShortName = strings.TrimSuffix(p.ShortName, ".s")
-		p.ShortName = strings.TrimSuffix(p.ShortName, ".cor")
+		var data []byte
+		if p.Champion != "" {
+			lib, err := library.Default()
+			if err != nil {
+				return fmt.Errorf("failed to load the library: %w", err)
+			}
+			e, err := lib.Find(p.Champion)
+			if err != nil {
+				return err
+			}
+			p.PathName = e.Path
+			p.ShortName = e.ShortName()
+			data = e.Src
+		} else {
+			tmp := strings.Split(p.PathName, "/")
+			p.ShortName = tmp[len(tmp)-1]
+			p.ShortName = strings.TrimSuffix(p.ShortName, ".s")
+			p.ShortName = strings.TrimSuffix(p.ShortName, ".cor")
 
-		data, err := os.ReadFile(p.PathName)
-		if err != nil {
-			return fmt.Errorf("failed to read file %q: %w", p.PathName, err)
+			buf, err := os.ReadFile(p.PathName)
+			if err != nil {
+				return fmt.Errorf("failed to read file %q: %w", p.PathName, err)
+			}
+			data = buf
 		}
 		if strings.HasSuffix(p.PathName, ".s") {
-			buf, pr, err := asm.Compile(p.PathName, string(data), false)
+			buf, pr, err := asm.Compile(p.PathName, string(data), parser.WarningOptions{})
 			if err != nil {
 				return fmt.Errorf("failed to compile %q: %w", p.PathName, err)
 			}
-			p.Prog = pr
+			p.SourceMap = asm.NewSourceMap(p.PathName, string(data), pr)
 			data = buf
+		} else if m, err := asm.LoadSourceMap(p.PathName + ".map"); err == nil {
+			p.SourceMap = m
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to load source map of %q: %w", p.PathName, err)
 		}
 		p.Data = data
 
-		prog, err := disasm.Disam(p.ShortName, data, false)
+		prog, err := disasm.Disam(p.ShortName, data, parser.WarningOptions{})
 		if err != nil {
 			return fmt.Errorf("failed to disassemble %q: %w", p.PathName, err)
 		}
@@ -133,13 +243,87 @@ func loadPlayers(players []*Player) error {
 	return nil
 }
 
-func ParseConfig() (vm.Config, []*Player, error) {
-	players, err := parse()
+// LoadReplay reads a replay file and disassembles its players.
+func LoadReplay(path string) (*vm.Replay, []*Player, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read replay %q: %w", path, err)
+	}
+	var r vm.Replay
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode replay %q: %w", path, err)
+	}
+
+	players, err := disamPlayers(path, r.Config.Players)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &r, players, nil
+}
+
+// LoadSnapshot restores the game saved in a snapshot file and disassembles
+// its players. The observer, history and ownership tracking are set from cfg,
+// the rest of the config comes from the snapshot.
+func LoadSnapshot(path string, cfg vm.Config) (*vm.Corewar, []*Player, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read snapshot %q: %w", path, err)
+	}
+	cw := &vm.Corewar{Config: cfg}
+	if err := cw.UnmarshalBinary(data); err != nil {
+		return nil, nil, fmt.Errorf("failed to restore snapshot %q: %w", path, err)
+	}
+	players, err := disamPlayers(path, cw.Config.Players)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cw, players, nil
+}
+
+// disamPlayers disassembles the players stored in a replay or a snapshot.
+func disamPlayers(path string, cfgs []vm.PlayerConfig) ([]*Player, error) {
+	players := make([]*Player, 0, len(cfgs))
+	for _, elem := range cfgs {
+		p := &Player{
+			PathName: path,
+			Number:   elem.Number,
+			Data:     elem.Data,
+		}
+		prog, err := disasm.Disam(fmt.Sprintf("player-%d", elem.Number), elem.Data, parser.WarningOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to disassemble player %d: %w", elem.Number, err)
+		}
+		p.Prog = prog
+		p.ShortName = prog.GetDirective(op.NameCmdString)
+		players = append(players, p)
+	}
+	return players, nil
+}
+
+// WriteReplay stores the replay as JSON.
+func WriteReplay(path string, r *vm.Replay) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode replay: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write replay %q: %w", path, err)
+	}
+	return nil
+}
+
+// ParseConfig parses the command line and loads the players.
+// With -replay or -load, only the options are set, see LoadReplay and LoadSnapshot.
+func ParseConfig() (vm.Config, []*Player, Options, error) {
+	players, opts, err := parse()
 	if err != nil {
-		return vm.Config{}, nil, fmt.Errorf("parse: %w", err)
+		return vm.Config{}, nil, opts, fmt.Errorf("parse: %w", err)
+	}
+	if opts.Replay != "" || opts.Snapshot != "" {
+		return vm.Config{}, nil, opts, nil
 	}
 	if err := loadPlayers(players); err != nil {
-		return vm.Config{}, nil, fmt.Errorf("load players: %w", err)
+		return vm.Config{}, nil, opts, fmt.Errorf("load players: %w", err)
 	}
 
 	cfg := vm.Config{
@@ -148,6 +332,7 @@ func ParseConfig() (vm.Config, []*Player, error) {
 		CyclesToDie: op.CyclesToDie,
 		CycleDelta:  op.CycleDelta,
 		NumLives:    op.NumLives,
+		MaxChecks:   op.MaxChecks,
 		Players:     make([]vm.PlayerConfig, 0, len(players)),
 	}
 	for _, p := range players {
@@ -156,5 +341,5 @@ func ParseConfig() (vm.Config, []*Player, error) {
 			Data:   p.Data,
 		})
 	}
-	return cfg, players, nil
+	return cfg, players, opts, nil
 }
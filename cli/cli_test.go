@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.creack.net/corewar/asm"
+	"go.creack.net/corewar/asm/parser"
+	"go.creack.net/corewar/op"
+	"go.creack.net/corewar/vm"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		numbers []int // Per player.
+		opts    Options
+		err     bool
+	}{
+		{name: "players", args: []string{"a.s", "b.cor"}, numbers: []int{1, 2}, opts: Options{DumpCycle: -1}},
+		{name: "numbers", args: []string{"-n", "3", "a.s", "-n1", "b.s", "c.s"}, numbers: []int{3, 1, 2}, opts: Options{DumpCycle: -1}},
+		{name: "dump", args: []string{"-dump", "42", "a.s"}, numbers: []int{1}, opts: Options{DumpCycle: 42, DumpWidth: 32}},
+		{name: "dump 64", args: []string{"a.s", "-d", "0"}, numbers: []int{1}, opts: Options{DumpCycle: 0, DumpWidth: 64}},
+		{name: "verbose", args: []string{"-v", "31", "a.s"}, numbers: []int{1}, opts: Options{DumpCycle: -1, Verbose: 31}},
+		{name: "invalid verbose", args: []string{"-v", "x", "a.s"}, err: true},
+		{name: "load", args: []string{"-load", "game.snap"}, opts: Options{DumpCycle: -1, Snapshot: "game.snap"}},
+		{name: "load with players", args: []string{"-load", "game.snap", "a.s"}, err: true},
+		{name: "load with replay", args: []string{"-load", "game.snap", "-replay", "game.json"}, err: true},
+		{name: "record", args: []string{"a.s", "-record", "game.json"}, numbers: []int{1}, opts: Options{DumpCycle: -1, Record: "game.json"}},
+		{name: "replay", args: []string{"-replay", "game.json"}, opts: Options{DumpCycle: -1, Replay: "game.json"}},
+		{name: "replay with players", args: []string{"-replay", "game.json", "a.s"}, err: true},
+		{name: "replay with record", args: []string{"-replay", "game.json", "-record", "other.json"}, err: true},
+		{name: "replay with dump", args: []string{"-replay", "game.json", "-dump", "42"}, err: true},
+		{name: "replay verbose", args: []string{"-v", "2", "-replay", "game.json"}, opts: Options{DumpCycle: -1, Verbose: 2, Replay: "game.json"}},
+		{name: "champion", args: []string{"-champ", "zork", "-n", "1", "a.s"}, numbers: []int{2, 1}, opts: Options{DumpCycle: -1}},
+		{name: "invalid dump", args: []string{"-dump", "-1", "a.s"}, err: true},
+		{name: "no players", args: []string{"-dump", "1"}, err: true},
+		{name: "invalid extension", args: []string{"a.txt"}, err: true},
+		{name: "invalid number", args: []string{"-n", "5", "a.s"}, err: true},
+		{name: "duplicate number", args: []string{"-n", "2", "a.s", "-n", "2", "b.s"}, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := os.Args
+			t.Cleanup(func() { os.Args = args })
+			os.Args = append([]string{"corewar"}, tt.args...)
+
+			players, opts, err := parse()
+			if tt.err {
+				if err == nil {
+					t.Fatalf("Expected an error, got %d players and %+v.", len(players), opts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to parse: %s.", err)
+			}
+			if opts != tt.opts {
+				t.Fatalf("Unexpected options: %+v, expected %+v.", opts, tt.opts)
+			}
+			if len(players) != len(tt.numbers) {
+				t.Fatalf("Got %d players, expected %d.", len(players), len(tt.numbers))
+			}
+			for i, p := range players {
+				if p.Number != tt.numbers[i] {
+					t.Fatalf("Player %q got number %d, expected %d.", p.PathName, p.Number, tt.numbers[i])
+				}
+			}
+		})
+	}
+}
+
+// testConfig returns the default config with zork as players 1 and 3.
+func testConfig(t *testing.T) vm.Config {
+	t.Helper()
+	buf, _, err := asm.Compile("test.s", ".name \"zork\"\n.comment \"just a basic living prog\"\n\nl2:\tlive\t%1\n\tzjmp\t%:l2\n", parser.WarningOptions{})
+	if err != nil {
+		t.Fatalf("Failed to compile: %s.", err)
+	}
+	return vm.Config{
+		MemSize:     op.MemSize,
+		IdxMod:      op.IdxMod,
+		CyclesToDie: op.CyclesToDie,
+		CycleDelta:  op.CycleDelta,
+		NumLives:    op.NumLives,
+		MaxChecks:   op.MaxChecks,
+		Players:     []vm.PlayerConfig{{Number: 1, Data: buf}, {Number: 3, Data: buf}},
+	}
+}
+
+func TestReplayFile(t *testing.T) {
+	r, err := vm.Record(context.Background(), testConfig(t), vm.DefaultReplayInterval)
+	if err != nil {
+		t.Fatalf("Failed to record: %s.", err)
+	}
+	path := filepath.Join(t.TempDir(), "game.json")
+	if err := WriteReplay(path, r); err != nil {
+		t.Fatalf("Failed to write the replay: %s.", err)
+	}
+
+	loaded, players, err := LoadReplay(path)
+	if err != nil {
+		t.Fatalf("Failed to load the replay: %s.", err)
+	}
+	if len(players) != 2 || players[1].Number != 3 || players[1].ShortName != "zork" {
+		t.Fatalf("Unexpected players: %+v.", players)
+	}
+	if _, err := loaded.Play(context.Background()); err != nil {
+		t.Fatalf("Failed to play the loaded replay: %s.", err)
+	}
+
+	if _, _, err := LoadReplay(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("Loaded a missing replay.")
+	}
+}
+
+func TestLoadSnapshot(t *testing.T) {
+	cw, err := vm.NewCorewar(testConfig(t))
+	if err != nil {
+		t.Fatalf("Failed to create the game: %s.", err)
+	}
+	if err := cw.RunUntil(context.Background(), 100); err != nil {
+		t.Fatalf("Failed to run: %s.", err)
+	}
+	data, err := cw.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to marshal: %s.", err)
+	}
+	path := filepath.Join(t.TempDir(), "game.snap")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write the snapshot: %s.", err)
+	}
+
+	loaded, players, err := LoadSnapshot(path, vm.Config{TrackOwnership: true})
+	if err != nil {
+		t.Fatalf("Failed to load the snapshot: %s.", err)
+	}
+	if loaded.Cycle != 100 || loaded.Owners == nil {
+		t.Fatalf("Restored cycle %d, ownership tracked %t, expected cycle 100 with ownership.", loaded.Cycle, loaded.Owners != nil)
+	}
+	if len(players) != 2 || players[1].Number != 3 || players[1].ShortName != "zork" {
+		t.Fatalf("Unexpected players: %+v.", players)
+	}
+
+	if _, _, err := LoadSnapshot(filepath.Join(t.TempDir(), "missing.snap"), vm.Config{}); err == nil {
+		t.Fatal("Loaded a missing snapshot.")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/rivo/tview"
 
 	"go.creack.net/corewar/asm"
+	"go.creack.net/corewar/asm/parser"
 	"go.creack.net/corewar/op"
 )
 
@@ -167,7 +168,7 @@ func run(input, output string, strict, prettyPrint bool) error {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	buf, pr, err := asm.Compile(input, string(data), strict)
+	buf, pr, err := asm.Compile(input, string(data), parser.WarningOptions{Error: strict})
 	if err != nil {
 		return fmt.Errorf("failed to compile: %w", err)
 	}
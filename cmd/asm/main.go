@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -8,28 +9,35 @@ import (
 	"strings"
 
 	"go.creack.net/corewar/asm"
+	"go.creack.net/corewar/asm/parser"
 )
 
-func run(input, output string, strict, prettyPrint bool) error {
+func run(input, output string, warnOpts parser.WarningOptions, prettyPrint, sourceMap bool) error {
 	data, err := os.ReadFile(input)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
-	buf, pr, err := asm.Compile(input, string(data), strict)
+	buf, pr, err := asm.Compile(input, string(data), warnOpts)
 	if err != nil {
-		return fmt.Errorf("failed to compile: %w", err)
+		return err
+	}
+	for _, d := range pr.Warnings() {
+		fmt.Fprintf(os.Stderr, "%s\n%s", d, d.Excerpt())
 	}
 	if prettyPrint {
-		for _, elem := range pr.Nodes {
-			fmt.Printf("%s\n", elem.PrettyPrint(pr.Nodes))
-		}
+		fmt.Print(parser.Format(pr.Nodes))
 		return nil
 	}
 
 	if err := os.WriteFile(output, buf, 0644); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
+	if sourceMap {
+		if err := asm.WriteSourceMap(output+".map", asm.NewSourceMap(input, string(data), pr)); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -51,8 +59,10 @@ func run(input, output string, strict, prettyPrint bool) error {
 func main() {
 	log.SetFlags(0)
 	output := flag.String("o", "", "output file, default to <input>.cor")
-	strict := flag.Bool("strict", false, "strict mode")
+	werror := flag.Bool("Werror", false, "treat the warnings as errors")
+	wno := flag.String("Wno", "", "comma separated warning codes to ignore, e.g. W001,W002")
 	prettyPrint := flag.Bool("pretty", false, "pretty print, do not output compiled file")
+	sourceMap := flag.Bool("map", false, "write the source map to <output>.map, for the debugger and the viewers")
 	flag.Parse()
 	input := flag.Arg(0)
 	if input == "" {
@@ -66,7 +76,16 @@ func main() {
 		*output = strings.ReplaceAll(input, ".s", ".cor")
 	}
 
-	if err := run(input, *output, *strict, *prettyPrint); err != nil {
+	warnOpts := parser.WarningOptions{Error: *werror}
+	warnOpts.Disable(*wno)
+	if err := run(input, *output, warnOpts, *prettyPrint, *sourceMap); err != nil {
+		var errs parser.ErrorList
+		if errors.As(err, &errs) {
+			for _, d := range errs {
+				fmt.Fprintf(os.Stderr, "%s\n%s", d, d.Excerpt())
+			}
+			os.Exit(1)
+		}
 		log.Fatalf("fail: %s.", err)
 	}
 }
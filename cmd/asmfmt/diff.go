@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// edit is a line of a diff.
+type edit struct {
+	op   byte // ' ', '-' or '+'.
+	text string
+}
+
+// lineDiff returns the edits turning a into b, based on the longest common subsequence.
+func lineDiff(a, b []string) []edit {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, edit{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, edit{'+', b[j]})
+	}
+	return edits
+}
+
+// unifiedDiff returns the diff between a and b in the unified format,
+// with 3 lines of context. Empty if they are the same.
+func unifiedDiff(name string, a, b []byte) string {
+	const context = 3
+
+	split := func(buf []byte) []string {
+		lines := strings.SplitAfter(string(buf), "\n")
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		return lines
+	}
+	edits := lineDiff(split(a), split(b))
+
+	out := &strings.Builder{}
+	for i := 0; i < len(edits); {
+		// Look for the next change.
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+
+		// Extend the hunk until there are more than twice the context of unchanged lines.
+		start := max(i-context, 0)
+		end := i
+		for same := 0; end < len(edits) && same <= 2*context; end++ {
+			if edits[end].op == ' ' {
+				same++
+			} else {
+				same = 0
+			}
+		}
+		// Keep only the trailing context.
+		for end > i && edits[end-1].op == ' ' {
+			end--
+		}
+		end = min(end+context, len(edits))
+
+		// Line numbers, starting at 1, of the hunk in each file.
+		aLine, bLine := 1, 1
+		for _, e := range edits[:start] {
+			if e.op != '+' {
+				aLine++
+			}
+			if e.op != '-' {
+				bLine++
+			}
+		}
+		var aLen, bLen int
+		for _, e := range edits[start:end] {
+			if e.op != '+' {
+				aLen++
+			}
+			if e.op != '-' {
+				bLen++
+			}
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(out, "--- %s.orig\n+++ %s\n", name, name)
+		}
+		fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", aLine, aLen, bLine, bLen)
+		for _, e := range edits[start:end] {
+			out.WriteByte(e.op)
+			out.WriteString(e.text)
+			if !strings.HasSuffix(e.text, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return out.String()
+}
@@ -0,0 +1,126 @@
+// Command asmfmt formats champion sources, like gofmt.
+//
+// Without path, it formats stdin to stdout. Directories are walked for .s files.
+// The formatted source is always verified to assemble to the same bytes.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.creack.net/corewar/asm"
+	"go.creack.net/corewar/asm/parser"
+)
+
+type options struct {
+	list  bool // List the files whose formatting differs.
+	write bool // Write the result to the source file.
+	diff  bool // Print the diff.
+}
+
+// printError prints the error, with all the diagnostics and their excerpt if any.
+func printError(err error) {
+	var errs parser.ErrorList
+	if errors.As(err, &errs) {
+		for _, d := range errs {
+			fmt.Fprintf(os.Stderr, "%s\n%s", d, d.Excerpt())
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s\n", err)
+}
+
+func processFile(path string, in io.Reader, out io.Writer, opts options) error {
+	src, err := io.ReadAll(in)
+	if err != nil {
+		return fmt.Errorf("failed to read %q: %w", path, err)
+	}
+
+	res, err := asm.Format(path, string(src))
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(src, res) {
+		if opts.list {
+			fmt.Fprintln(out, path)
+		}
+		if opts.write {
+			if err := os.WriteFile(path, res, 0o644); err != nil {
+				return fmt.Errorf("failed to write %q: %w", path, err)
+			}
+		}
+		if opts.diff {
+			fmt.Fprint(out, unifiedDiff(path, src, res))
+		}
+	}
+	if !opts.list && !opts.write && !opts.diff {
+		if _, err := out.Write(res); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+	return nil
+}
+
+func processPath(path string, opts options) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %q: %w", path, err)
+	}
+	defer func() { _ = f.Close() }() // Best effort.
+	return processFile(path, f, os.Stdout, opts)
+}
+
+func main() {
+	var opts options
+	flag.BoolVar(&opts.list, "l", false, "list files whose formatting differs")
+	flag.BoolVar(&opts.write, "w", false, "write result to the source file instead of stdout")
+	flag.BoolVar(&opts.diff, "d", false, "display diffs instead of rewriting files")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: asmfmt [flags] [path ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		if opts.write {
+			fmt.Fprintln(os.Stderr, "error: cannot use -w with standard input")
+			os.Exit(2)
+		}
+		if err := processFile("<stdin>", os.Stdin, os.Stdout, opts); err != nil {
+			printError(err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	exitCode := 0
+	for _, arg := range flag.Args() {
+		err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// Only .s files when walking directories, any file when given explicitly.
+			if d.IsDir() || (path != arg && !strings.HasSuffix(path, ".s")) {
+				return nil
+			}
+			if err := processPath(path, opts); err != nil {
+				printError(err)
+				exitCode = 2
+			}
+			return nil
+		})
+		if err != nil {
+			printError(err)
+			exitCode = 2
+		}
+	}
+	os.Exit(exitCode)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	src       = ".name \"t\"\n.comment \"c\"\n\nl: live %1\n\tzjmp %:l\n"
+	formatted = ".name \"t\"\n.comment \"c\"\n\nl:\n\tlive    %1\n\tzjmp    %:l\n"
+)
+
+func TestProcessFile(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		opts options
+		want string
+	}{
+		{name: "format", src: src, want: formatted},
+		{name: "list", src: src, opts: options{list: true}, want: "t.s\n"},
+		{name: "list formatted", src: formatted, opts: options{list: true}},
+		{name: "diff", src: src, opts: options{diff: true}, want: `--- t.s.orig
++++ t.s
+@@ -1,5 +1,6 @@
+ .name "t"
+ .comment "c"
+ 
+-l: live %1
+-	zjmp %:l
++l:
++	live    %1
++	zjmp    %:l
+`},
+		{name: "diff formatted", src: formatted, opts: options{diff: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := processFile("t.s", strings.NewReader(tt.src), &out, tt.opts); err != nil {
+				t.Fatalf("Failed to process: %s.", err)
+			}
+			if out.String() != tt.want {
+				t.Fatalf("Unexpected output:\n%s\nexpected:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessFileWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "t.s")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatalf("Failed to write the source: %s.", err)
+	}
+	if err := processPath(path, options{write: true}); err != nil {
+		t.Fatalf("Failed to process: %s.", err)
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read the result: %s.", err)
+	}
+	if string(buf) != formatted {
+		t.Fatalf("Unexpected result:\n%s\nexpected:\n%s", buf, formatted)
+	}
+}
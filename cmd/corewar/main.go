@@ -1,11 +1,14 @@
 package main
 
 import (
-	"bytes"
+	"bufio"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"os"
 	"strings"
 	"time"
 
@@ -13,99 +16,176 @@ import (
 	"go.creack.net/corewar/vm"
 )
 
-func dump(vm []byte, pc uint32) {
-	zz := make([]byte, 32)
-	for i := 0; i < len(vm); {
-		b := vm[i]
-		if i%32 == 0 {
-			if bytes.Equal(vm[i:i+32], zz) {
-				fmt.Printf("\n*")
-				for ; i < len(vm) && bytes.Equal(vm[i:i+32], zz); i += 32 {
-				}
-				continue
+// dump writes the memory in hexadecimal, width bytes per line.
+//
+// The format is stable and matches the classic corewar VM:
+// each line starts with the address of its first byte as "0x%04x : ",
+// followed by each byte as "%02x " (lowercase, trailing space included).
+func dump(w io.Writer, ram []byte, width int) error {
+	bw := bufio.NewWriter(w)
+	for i, b := range ram {
+		if i%width == 0 {
+			if i != 0 {
+				_ = bw.WriteByte('\n') // Error checked on flush.
 			}
-			fmt.Printf("\n0x%04X:", i)
+			fmt.Fprintf(bw, "0x%04x : ", i)
 		}
-		if i == int(pc) {
-			fmt.Printf("\033[7m")
-		}
-		fmt.Printf(" %02x", b)
-		if i == int(pc) {
-			fmt.Printf("\033[27m")
-		}
-		i++
+		fmt.Fprintf(bw, "%02x ", b)
+	}
+	_ = bw.WriteByte('\n') // Error checked on flush.
+	return bw.Flush()
+}
+
+// printer prints the game events to stdout.
+type printer struct {
+	vm.NopObserver
+}
+
+func (printer) OnLive(e vm.LiveEvent) {
+	if e.Player == nil {
+		fmt.Printf("Missed 'live' from %d (%s)\n", e.Process.Player.Number, e.Process.Player.Name)
+		return
 	}
-	fmt.Printf("\n")
+	fmt.Printf("Player %d (%s) is alive\n", e.Player.Number, e.Player.Name)
+}
+
+func (printer) OnDisplay(e vm.DisplayEvent) {
+	fmt.Printf("%c\n", e.Char)
+}
+
+func (printer) OnDeath(e vm.DeathEvent) {
+	fmt.Printf("Player %d (%s) died\n", e.Player.Number, e.Player.Name)
 }
 
-func run(ctx context.Context, cw *vm.Corewar) error {
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+func (printer) OnGameOver(e vm.GameOverEvent) {
+	names := make([]string, 0, len(e.Alive))
+	for _, elem := range e.Alive {
+		names = append(names, fmt.Sprintf("%d (%s)", elem.Number, elem.Name))
+	}
+	fmt.Printf("Game over (%s), %d players alive: %s\n", e.Reason, len(e.Alive), strings.Join(names, ","))
+	if e.Winner != nil {
+		fmt.Printf("Player %d (%s) won\n", e.Winner.Number, e.Winner.Name)
+	}
+}
 
+func run(ctx context.Context, cfg vm.Config, opts cli.Options) error {
 	start := time.Now()
-	done := make(chan struct{})
-	go func() {
-		defer close(done)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case msg, ok := <-cw.Messages:
-				if !ok {
-					return
-				}
-				switch msg.Type {
-				case vm.MsgDebug:
-				// case vm.MsgLive, vm.MsgLiveMiss:
-				case vm.MsgGameOver:
-					fmt.Printf("%s\n", msg.Message)
-					fmt.Printf("Cycles: %d in %s.\n", cw.Cycle, time.Since(start))
-					return
-				default:
-					fmt.Println(strings.TrimSuffix(msg.Message, "\n"))
-				}
-			}
-		}
-	}()
-
-	for {
-		select {
-		case <-ctx.Done():
-			break
-		case <-done:
-			break
-		default:
+
+	// Dump mode, run the requested cycle and dump the memory.
+	// Nothing else is displayed to keep the output comparable,
+	// unless the game ends before, then the winner is displayed instead.
+	if opts.DumpCycle >= 0 {
+		cw, err := vm.NewCorewar(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to load players: %w", err)
 		}
-		if err := cw.Round(); err != nil {
-			if err == io.EOF {
-				break
+		// Like the classic VM, the dump shows the memory once the
+		// instructions of the cycle ran, i.e. when the next one starts.
+		if err := cw.RunUntil(ctx, opts.DumpCycle+1); err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Printf("Player %d (%s) won\n", cw.Winner.Number, cw.Winner.Name)
+				return nil
 			}
-			return fmt.Errorf("failed to execute round: %w", err)
+			return fmt.Errorf("failed to run game: %w", err)
 		}
+		return dump(os.Stdout, cw.Ram, opts.DumpWidth)
+	}
+
+	obs, flush := newObserver(opts.Verbose, cfg.MemSize)
+	cfg.Observer = obs
+	var res vm.Result
+	var err error
+	if opts.Record != "" {
+		var r *vm.Replay
+		if r, err = vm.Record(ctx, cfg, vm.DefaultReplayInterval); err == nil {
+			res = r.Result
+			err = cli.WriteReplay(opts.Record, r)
+		}
+	} else {
+		res, err = vm.Run(ctx, cfg)
+	}
+	if err1 := flush(); err == nil && err1 != nil {
+		err = err1
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run game: %w", err)
 	}
-	close(cw.Messages)
-	select {
-	case <-done:
-	case <-ctx.Done():
+	// In verbose mode, only the trace is displayed, followed by the winner.
+	if opts.Verbose == 0 {
+		fmt.Printf("Cycles: %d in %s.\n", res.Cycle, time.Since(start))
 	}
+	return nil
+}
+
+// newObserver returns the observer for the verbosity level
+// and the function to call once the game is over.
+func newObserver(verbose, memSize int) (vm.Observer, func() error) {
+	if verbose == 0 {
+		return printer{}, func() error { return nil }
+	}
+	t := &tracer{w: bufio.NewWriter(os.Stdout), level: verbose, memSize: memSize}
+	return t, t.w.Flush
+}
+
+// replay plays a replay file and verifies it.
+// Usage: corewar replay [-v N] file.
+func replay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	verbose := fs.Int("v", 0, "Verbosity bitmask, same as the game.")
+	_ = fs.Parse(args) // Exits on error.
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: corewar replay [-v N] file")
+	}
+
+	return playReplay(ctx, fs.Arg(0), *verbose)
+}
 
+// playReplay plays the replay file and verifies it, see replay.
+func playReplay(ctx context.Context, path string, verbose int) error {
+	r, _, err := cli.LoadReplay(path)
+	if err != nil {
+		return err
+	}
+	obs, flush := newObserver(verbose, r.Config.MemSize)
+	r.Config.Observer = obs
+	res, err := r.Play(ctx)
+	if err1 := flush(); err == nil && err1 != nil {
+		err = err1
+	}
+	if err != nil {
+		return fmt.Errorf("failed to replay game: %w", err)
+	}
+	if verbose == 0 {
+		fmt.Printf("Replay verified, %d hashes up to cycle %d.\n", len(r.Hashes), res.Cycle)
+	}
 	return nil
 }
 
 func main() {
 	ctx := context.Background()
 
-	cfg, _, err := cli.ParseConfig()
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := replay(ctx, os.Args[2:]); err != nil {
+			log.Fatal("Fail:", err.Error())
+		}
+		return
+	}
+
+	cfg, _, opts, err := cli.ParseConfig()
 	if err != nil {
 		log.Fatalf("Failed to parse CLI config: %s.", err)
 	}
-
-	cw := vm.NewCorewar(cfg)
-	if err := cw.Round(); err != nil {
-		log.Fatalf("Failed to execute first round: %s.", err)
+	if opts.Snapshot != "" {
+		log.Fatalf("Snapshots can't be resumed headless, use vm-viewer -load.")
+	}
+	if opts.Replay != "" {
+		if err := playReplay(ctx, opts.Replay, opts.Verbose); err != nil {
+			log.Fatal("Fail:", err.Error())
+		}
+		return
 	}
 
-	if err := run(ctx, cw); err != nil {
+	if err := run(ctx, cfg, opts); err != nil {
 		log.Fatal("Fail:", err.Error())
 		return
 	}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.creack.net/corewar/asm"
+	"go.creack.net/corewar/asm/parser"
+	"go.creack.net/corewar/cli"
+	"go.creack.net/corewar/op"
+	"go.creack.net/corewar/vm"
+)
+
+const quitter = `.name "quitter"
+.comment "lives once"
+
+	sti	r1, %:live, %1
+	ld	%0, r2
+live:	live	%1
+wait:	zjmp	%:wait
+`
+
+// testConfig returns the default config with the given champions, numbered in order.
+func testConfig(t *testing.T, srcs ...string) vm.Config {
+	t.Helper()
+	cfg := vm.Config{
+		MemSize:     op.MemSize,
+		IdxMod:      op.IdxMod,
+		CyclesToDie: op.CyclesToDie,
+		CycleDelta:  op.CycleDelta,
+		NumLives:    op.NumLives,
+		MaxChecks:   op.MaxChecks,
+	}
+	for i, src := range srcs {
+		buf, _, err := asm.Compile("test.s", src, parser.WarningOptions{})
+		if err != nil {
+			t.Fatalf("Failed to compile: %s.", err)
+		}
+		cfg.Players = append(cfg.Players, vm.PlayerConfig{Number: i + 1, Data: buf})
+	}
+	return cfg
+}
+
+// captureStdout returns what fn printed on stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatalf("Failed to create the output file: %s.", err)
+	}
+	defer func() { _ = f.Close() }() // Best effort.
+
+	stdout := os.Stdout
+	os.Stdout = f
+	defer func() { os.Stdout = stdout }()
+	fn()
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Failed to rewind the output: %s.", err)
+	}
+	buf, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("Failed to read the output: %s.", err)
+	}
+	return string(buf)
+}
+
+func TestDump(t *testing.T) {
+	ram := []byte{0x00, 0x01, 0x0a, 0xff, 0x10, 0x20, 0x30, 0x40}
+	tests := []struct {
+		width int
+		want  string
+	}{
+		{width: 4, want: "0x0000 : 00 01 0a ff \n0x0004 : 10 20 30 40 \n"},
+		{width: 8, want: "0x0000 : 00 01 0a ff 10 20 30 40 \n"},
+	}
+	for _, tt := range tests {
+		var buf strings.Builder
+		if err := dump(&buf, ram, tt.width); err != nil {
+			t.Fatalf("Failed to dump: %s.", err)
+		}
+		if buf.String() != tt.want {
+			t.Fatalf("Unexpected dump with width %d:\n%s\nexpected:\n%s", tt.width, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestRunDump(t *testing.T) {
+	cfg := testConfig(t, quitter, quitter)
+	code := cfg.Players[0].Data[len(cfg.Players[0].Data)-22:]
+
+	out := captureStdout(t, func() {
+		if err := run(context.Background(), cfg, cli.Options{DumpCycle: 0, DumpWidth: 64}); err != nil {
+			t.Fatalf("Failed to run: %s.", err)
+		}
+	})
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != op.MemSize/64 {
+		t.Fatalf("Got %d lines, expected %d.", len(lines), op.MemSize/64)
+	}
+	// The champion is loaded at the start of the memory.
+	var want strings.Builder
+	if err := dump(&want, code, 64); err != nil {
+		t.Fatalf("Failed to dump: %s.", err)
+	}
+	if !strings.HasPrefix(lines[0], strings.TrimSuffix(want.String(), "\n")) {
+		t.Fatalf("Unexpected first line: %q, expected the champion %q.", lines[0], want.String())
+	}
+
+	// The game ends before the cycle, the winner is displayed instead.
+	out = captureStdout(t, func() {
+		if err := run(context.Background(), cfg, cli.Options{DumpCycle: 1 << 20, DumpWidth: 32}); err != nil {
+			t.Fatalf("Failed to run: %s.", err)
+		}
+	})
+	if want := "Player 2 (quitter) won\n"; out != want {
+		t.Fatalf("Unexpected output: %q, expected %q.", out, want)
+	}
+}
+
+func TestRunDumpCycle(t *testing.T) {
+	cfg := testConfig(t, quitter, quitter)
+
+	// Find the cycle of the first write.
+	cw, err := vm.NewCorewar(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create the game: %s.", err)
+	}
+	initial := slices.Clone(cw.Ram)
+	cycle := 1
+	for ; slices.Equal(cw.Ram, initial); cycle++ {
+		if err := cw.RunUntil(context.Background(), cycle); err != nil {
+			t.Fatalf("Failed to run until cycle %d: %s.", cycle, err)
+		}
+	}
+	cycle -= 2 // The loop stopped after the cycle of the write ran.
+
+	dumpAt := func(cycle int) string {
+		return captureStdout(t, func() {
+			if err := run(context.Background(), cfg, cli.Options{DumpCycle: cycle, DumpWidth: 32}); err != nil {
+				t.Fatalf("Failed to run: %s.", err)
+			}
+		})
+	}
+	var before, after strings.Builder
+	if err := dump(&before, initial, 32); err != nil {
+		t.Fatalf("Failed to dump: %s.", err)
+	}
+	if err := dump(&after, cw.Ram, 32); err != nil {
+		t.Fatalf("Failed to dump: %s.", err)
+	}
+	// The dump of a cycle includes the instructions of that cycle.
+	if out := dumpAt(cycle - 1); out != before.String() {
+		t.Fatalf("Dump of cycle %d includes the write of cycle %d.", cycle-1, cycle)
+	}
+	if out := dumpAt(cycle); out != after.String() {
+		t.Fatalf("Dump of cycle %d misses its write.", cycle)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.json")
+	out := captureStdout(t, func() {
+		if err := run(context.Background(), testConfig(t, quitter, quitter), cli.Options{DumpCycle: -1, Record: path}); err != nil {
+			t.Fatalf("Failed to run: %s.", err)
+		}
+	})
+	if !strings.Contains(out, "Player 2 (quitter) won\n") {
+		t.Fatalf("Unexpected output: %q.", out)
+	}
+
+	out = captureStdout(t, func() {
+		if err := replay(context.Background(), []string{path}); err != nil {
+			t.Fatalf("Failed to replay: %s.", err)
+		}
+	})
+	if !strings.Contains(out, "Replay verified, ") {
+		t.Fatalf("Unexpected output: %q.", out)
+	}
+
+	// Same with -replay.
+	out = captureStdout(t, func() {
+		if err := playReplay(context.Background(), path, traceLives); err != nil {
+			t.Fatalf("Failed to replay: %s.", err)
+		}
+	})
+	if want := "Player 1 (quitter) is said to be alive\nPlayer 2 (quitter) is said to be alive\n"; !strings.HasPrefix(out, want) {
+		t.Fatalf("Unexpected output: %q, expected the lives %q.", out, want)
+	}
+}
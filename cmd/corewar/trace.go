@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+
+	"go.creack.net/corewar/vm"
+)
+
+// Verbosity levels, combined as a bitmask with -v.
+// Mirrors the classic corewar VM so the outputs can be diffed.
+const (
+	traceLives  = 1 << iota // Show lives.
+	traceCycles             // Show cycles.
+	traceOps                // Show operations with their resolved arguments.
+	traceDeaths             // Show deaths.
+	traceMoves              // Show PC movements, except for successful zjmp.
+)
+
+// tracer prints the game events in the classic verbose format.
+type tracer struct {
+	vm.NopObserver
+	w       *bufio.Writer
+	level   int
+	memSize int // To wrap the addresses like the PC.
+}
+
+func (t *tracer) OnCycle(e vm.CycleEvent) {
+	if t.level&traceCycles != 0 {
+		fmt.Fprintf(t.w, "It is now cycle %d\n", e.Cycle)
+	}
+}
+
+func (t *tracer) OnCheck(e vm.CheckEvent) {
+	if t.level&traceCycles != 0 && e.Decreased {
+		fmt.Fprintf(t.w, "Cycle to die is now %d\n", e.CyclesToDie)
+	}
+}
+
+func (t *tracer) OnLive(e vm.LiveEvent) {
+	if t.level&traceLives != 0 && e.Player != nil {
+		fmt.Fprintf(t.w, "Player %d (%s) is said to be alive\n", e.Player.Number, e.Player.Name)
+	}
+}
+
+func (t *tracer) OnDeath(e vm.DeathEvent) {
+	if t.level&traceDeaths == 0 {
+		return
+	}
+	for _, p := range e.Processes {
+		fmt.Fprintf(t.w, "Process %d hasn't lived for %d cycles (CTD %d)\n", p.ID, e.Cycle-p.LastLive, e.CyclesToDie)
+	}
+}
+
+func (t *tracer) OnMove(e vm.MoveEvent) {
+	if t.level&traceMoves == 0 {
+		return
+	}
+	fmt.Fprintf(t.w, "ADV %d (0x%04x -> 0x%04x) ", len(e.Bytes), int(e.From)%t.memSize, (int(e.From)+len(e.Bytes))%t.memSize)
+	for _, b := range e.Bytes {
+		fmt.Fprintf(t.w, "%02x ", b)
+	}
+	_ = t.w.WriteByte('\n') // Error checked on flush.
+}
+
+func (t *tracer) OnExec(e vm.ExecEvent) {
+	if t.level&traceOps == 0 {
+		return
+	}
+	ins, v := e.Instruction, e.Values
+	reg := func(i int) int64 { return ins.Params[i].Value }
+
+	name := ins.OpCode.Name
+	fmt.Fprintf(t.w, "P %4d | %s ", e.Process.ID, name)
+	switch name {
+	case "live", "fork", "lfork":
+		fmt.Fprintf(t.w, "%d", v[0])
+		if name != "live" {
+			fmt.Fprintf(t.w, " (%d)", e.Target)
+		}
+	case "ld", "lld":
+		fmt.Fprintf(t.w, "%d r%d", v[0], reg(1))
+	case "st":
+		fmt.Fprintf(t.w, "r%d %d", reg(0), v[1])
+	case "add", "sub":
+		fmt.Fprintf(t.w, "r%d r%d r%d", reg(0), reg(1), reg(2))
+	case "and", "or", "xor":
+		fmt.Fprintf(t.w, "%d %d r%d", v[0], v[1], reg(2))
+	case "zjmp":
+		fmt.Fprintf(t.w, "%d ", v[0])
+		if e.Jumped {
+			fmt.Fprint(t.w, "OK")
+		} else {
+			fmt.Fprint(t.w, "FAILED")
+		}
+	case "ldi", "lldi":
+		fmt.Fprintf(t.w, "%d %d r%d\n", v[0], v[1], reg(2))
+		fmt.Fprintf(t.w, "       | -> load from %d + %d = %d ", v[0], v[1], e.Offset)
+		if name == "ldi" {
+			fmt.Fprintf(t.w, "(with pc and mod %d)", e.Target)
+		} else {
+			fmt.Fprintf(t.w, "(with pc %d)", e.Target)
+		}
+	case "sti":
+		fmt.Fprintf(t.w, "r%d %d %d\n", reg(0), v[1], v[2])
+		fmt.Fprintf(t.w, "       | -> store to %d + %d = %d (with pc and mod %d)", v[1], v[2], e.Offset, e.Target)
+	case "aff":
+		fmt.Fprintf(t.w, "r%d", reg(0))
+	}
+	_ = t.w.WriteByte('\n') // Error checked on flush.
+}
+
+func (t *tracer) OnGameOver(e vm.GameOverEvent) {
+	if e.Winner != nil {
+		fmt.Fprintf(t.w, "Player %d (%s) won\n", e.Winner.Number, e.Winner.Name)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.creack.net/corewar/library"
+	"go.creack.net/corewar/vm"
+)
+
+var update = flag.Bool("update", false, "Update the golden files.")
+
+// runner lives once and runs into an invalid byte, then the empty memory.
+const runner = `.name "runner"
+.comment "runs away"
+.extend
+
+	live	%1
+.code ff
+`
+
+// trace plays the game up to the cycle and returns the trace for the level.
+func trace(t *testing.T, cfg vm.Config, level, cycle int) string {
+	t.Helper()
+	var buf strings.Builder
+	tr := &tracer{w: bufio.NewWriter(&buf), level: level, memSize: cfg.MemSize}
+	cfg.Observer = tr
+	cw, err := vm.NewCorewar(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create the game: %s.", err)
+	}
+	if err := cw.RunUntil(context.Background(), cycle); err != nil && !errors.Is(err, io.EOF) {
+		t.Fatalf("Failed to run until cycle %d: %s.", cycle, err)
+	}
+	if err := tr.w.Flush(); err != nil {
+		t.Fatalf("Failed to flush the trace: %s.", err)
+	}
+	return buf.String()
+}
+
+func TestTrace(t *testing.T) {
+	tests := []struct {
+		name  string
+		level int
+		cycle int
+		want  string
+	}{
+		{name: "none", level: 0, cycle: 45, want: ""},
+		{name: "lives", level: traceLives, cycle: 45, want: `Player 1 (quitter) is said to be alive
+Player 2 (quitter) is said to be alive
+`},
+		{name: "cycles", level: traceCycles, cycle: 3, want: `It is now cycle 1
+It is now cycle 2
+It is now cycle 3
+`},
+		{name: "operations", level: traceOps, cycle: 45, want: `P    1 | sti r1 14 1
+       | -> store to 14 + 1 = 15 (with pc and mod 15)
+P    2 | sti r1 14 1
+       | -> store to 14 + 1 = 15 (with pc and mod 2063)
+P    1 | ld 0 r2
+P    2 | ld 0 r2
+P    1 | live 1
+P    2 | live 2
+`},
+		{name: "moves", level: traceMoves, cycle: 45, want: `ADV 7 (0x0000 -> 0x0007) 0b 68 01 00 0e 00 01 
+ADV 7 (0x0800 -> 0x0807) 0b 68 01 00 0e 00 01 
+ADV 7 (0x0007 -> 0x000e) 02 90 00 00 00 00 02 
+ADV 7 (0x0807 -> 0x080e) 02 90 00 00 00 00 02 
+ADV 5 (0x000e -> 0x0013) 01 00 00 00 01 
+ADV 5 (0x080e -> 0x0813) 01 00 00 00 02 
+`},
+		{name: "deaths", level: traceDeaths, cycle: 1 << 20, want: `Process 1 hasn't lived for 3032 cycles (CTD 1536)
+Process 2 hasn't lived for 3032 cycles (CTD 1536)
+Player 2 (quitter) won
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trace(t, testConfig(t, quitter, quitter), tt.level, tt.cycle); got != tt.want {
+				t.Fatalf("Unexpected trace:\n%s\nexpected:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTraceMoveWrap(t *testing.T) {
+	var buf strings.Builder
+	tr := &tracer{w: bufio.NewWriter(&buf), level: traceMoves, memSize: 4096}
+	tr.OnMove(vm.MoveEvent{From: 4094, Bytes: []byte{0x01, 0x00, 0x00, 0x00, 0x01}})
+	if err := tr.w.Flush(); err != nil {
+		t.Fatalf("Failed to flush the trace: %s.", err)
+	}
+	if want := "ADV 5 (0x0ffe -> 0x0003) 01 00 00 00 01 \n"; buf.String() != want {
+		t.Fatalf("Unexpected trace: %q, expected %q.", buf.String(), want)
+	}
+}
+
+func TestTraceGolden(t *testing.T) {
+	lib, err := library.Default()
+	if err != nil {
+		t.Fatalf("Failed to load the library: %s.", err)
+	}
+	zork, err := lib.Find("zork")
+	if err != nil {
+		t.Fatalf("Failed to find zork: %s.", err)
+	}
+	if err := zork.Compile(); err != nil {
+		t.Fatalf("Failed to compile zork: %s.", err)
+	}
+	cfg := testConfig(t, runner)
+	cfg.Players = append(cfg.Players, vm.PlayerConfig{Number: 2, Data: zork.Bin})
+
+	got := trace(t, cfg, traceLives|traceOps|traceDeaths|traceMoves, 100)
+	path := filepath.Join("testdata", "trace.golden")
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("Failed to update the golden file: %s.", err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read the golden file: %s.", err)
+	}
+	if got != string(want) {
+		t.Fatalf("Unexpected trace:\n%s\nexpected:\n%s", got, want)
+	}
+}
@@ -0,0 +1,226 @@
+// Command corpus manages a collection of champion sources,
+// the embedded one by default, or a directory with -dir.
+//
+// Usage:
+//
+//	corpus [-dir path] list
+//	corpus [-dir path] info name ...
+//	corpus [-dir path] extract [-o dir] name ...
+//	corpus [-dir path] compile [-o dir]
+//	corpus [-dir path] dedupe
+//
+// Names are file names, with or without the hash of the program and the .s extension,
+// or close enough to a champion name, see library.Lookup.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.creack.net/corewar/library"
+)
+
+// errFailed is returned when some champions failed, after reporting them.
+var errFailed = errors.New("failed")
+
+// lookup returns the entries matching the given names, see library.Lookup.
+func lookup(lib *library.Library, names []string) ([]*library.Entry, error) {
+	var out []*library.Entry
+	for _, name := range names {
+		matches := lib.Lookup(name)
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%w: %q", library.ErrNotFound, name)
+		}
+		out = append(out, matches...)
+	}
+	return out, nil
+}
+
+// list prints the file name of each champion.
+func list(lib *library.Library, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	_ = fs.Parse(args) // Exits on error.
+	for _, e := range lib.Entries {
+		fmt.Println(e.Base())
+	}
+	return nil
+}
+
+// info prints the metadata of the given champions.
+func info(lib *library.Library, args []string) error {
+	fs := flag.NewFlagSet("info", flag.ExitOnError)
+	_ = fs.Parse(args) // Exits on error.
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: corpus info name ...")
+	}
+	matches, err := lookup(lib, fs.Args())
+	if err != nil {
+		return err
+	}
+	for i, e := range matches {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("file:    %s\n", e.Path)
+		if err := e.Compile(); err != nil {
+			fmt.Printf("error:   %s\n", err)
+			continue
+		}
+		fmt.Printf("name:    %s\n", e.Name)
+		fmt.Printf("comment: %s\n", e.Comment)
+		fmt.Printf("size:    %d\n", e.Size())
+		fmt.Printf("md5:     %s\n", e.Hash)
+	}
+	return nil
+}
+
+// extract prints the source of the given champion,
+// or writes the sources of the given champions to a directory.
+func extract(lib *library.Library, args []string) error {
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+	outDir := fs.String("o", "", "Directory to write the sources to, instead of stdout.")
+	_ = fs.Parse(args) // Exits on error.
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: corpus extract [-o dir] name ...")
+	}
+	matches, err := lookup(lib, fs.Args())
+	if err != nil {
+		return err
+	}
+	if *outDir == "" {
+		if len(matches) > 1 {
+			names := make([]string, 0, len(matches))
+			for _, e := range matches {
+				names = append(names, e.Base())
+			}
+			return fmt.Errorf("%d champions match, use -o or one of: %s", len(matches), strings.Join(names, ", "))
+		}
+		_, err := os.Stdout.Write(matches[0].Src)
+		return err
+	}
+	for _, e := range matches {
+		path := filepath.Join(*outDir, e.Base()+".s")
+		if err := os.WriteFile(path, e.Src, 0o644); err != nil {
+			return fmt.Errorf("failed to write %q: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// compileAll compiles every champion and reports the failures.
+func compileAll(lib *library.Library, args []string) error {
+	fs := flag.NewFlagSet("compile", flag.ExitOnError)
+	outDir := fs.String("o", "", "Directory to write the compiled champions to.")
+	_ = fs.Parse(args) // Exits on error.
+
+	failed := 0
+	for _, e := range lib.Entries {
+		if err := e.Compile(); err != nil {
+			failed++
+			fmt.Printf("FAIL %s: %s\n", e.Path, err)
+			continue
+		}
+		if *outDir == "" {
+			continue
+		}
+		path := filepath.Join(*outDir, e.Base()+".cor")
+		if err := os.WriteFile(path, e.Bin, 0o644); err != nil {
+			return fmt.Errorf("failed to write %q: %w", path, err)
+		}
+	}
+	fmt.Printf("%d/%d champions compiled.\n", len(lib.Entries)-failed, len(lib.Entries))
+	if failed > 0 {
+		return errFailed
+	}
+	return nil
+}
+
+// dedupe reports the champions compiling to the same program, and the
+// ones whose file name has a different hash, since the disassembler
+// looks up the known sources by that hash.
+func dedupe(lib *library.Library, args []string) error {
+	fs := flag.NewFlagSet("dedupe", flag.ExitOnError)
+	_ = fs.Parse(args) // Exits on error.
+
+	byHash := map[string][]string{} // md5 of the program -> file names.
+	var hashes []string
+	failed := 0
+	for _, e := range lib.Entries {
+		if err := e.Compile(); err != nil {
+			fmt.Printf("FAIL %s: %s\n", e.Path, err)
+			failed++
+			continue
+		}
+		if h := e.FileHash(); h != "" && h != e.Hash {
+			fmt.Printf("HASH %s: program hash is %s\n", e.Path, e.Hash)
+			failed++
+		}
+		if _, ok := byHash[e.Hash]; !ok {
+			hashes = append(hashes, e.Hash)
+		}
+		byHash[e.Hash] = append(byHash[e.Hash], e.Base())
+	}
+	slices.Sort(hashes)
+	dups := 0
+	for _, h := range hashes {
+		if names := byHash[h]; len(names) > 1 {
+			dups++
+			fmt.Printf("DUP %s: %s\n", h, strings.Join(names, ", "))
+		}
+	}
+	fmt.Printf("%d unique programs, %d duplicated.\n", len(hashes), dups)
+	if failed > 0 || dups > 0 {
+		return errFailed
+	}
+	return nil
+}
+
+func main() {
+	dir := flag.String("dir", "", "Directory of champion sources to use instead of the embedded corpus.")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: corpus [-dir path] list|info|extract|compile|dedupe [args]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	commands := map[string]func(*library.Library, []string) error{
+		"list":    list,
+		"info":    info,
+		"extract": extract,
+		"compile": compileAll,
+		"dedupe":  dedupe,
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var lib *library.Library
+	var err error
+	if *dir != "" {
+		lib, err = library.LoadDir(*dir)
+	} else {
+		lib, err = library.Default()
+	}
+	if err != nil {
+		log.Fatalf("Failed to load the champions: %s.", err)
+	}
+
+	if err := cmd(lib, flag.Args()[1:]); err != nil {
+		if errors.Is(err, errFailed) {
+			os.Exit(1)
+		}
+		log.Fatal("Fail:", err.Error())
+	}
+}
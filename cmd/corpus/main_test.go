@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.creack.net/corewar/library"
+)
+
+// champion returns the source of a champion, the live value makes its program unique.
+func champion(name string, live int) []byte {
+	return fmt.Appendf(nil, ".name %q\n.comment \"test\"\n\nl:\tlive\t%%%d\n\tzjmp\t%%:l\n", name, live)
+}
+
+// testLibrary returns a library with a duplicated program, a wrong hash
+// in a file name and a broken source.
+func testLibrary() *library.Library {
+	const zorkHash = "47cecba4085e9c137095663ff6402dcd"
+	return library.New([]*library.Entry{
+		{Path: "srcs/zork===" + zorkHash + ".s", Src: champion("zork", 1)},
+		{Path: "srcs/copy.s", Src: champion("zork", 1)},
+		{Path: "srcs/liar===" + zorkHash + ".s", Src: champion("liar", 2)},
+		{Path: "srcs/broken.s", Src: []byte("not a champion\n")},
+	})
+}
+
+// captureStdout returns what fn printed on stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatalf("Failed to create the output file: %s.", err)
+	}
+	defer func() { _ = f.Close() }() // Best effort.
+
+	stdout := os.Stdout
+	os.Stdout = f
+	defer func() { os.Stdout = stdout }()
+	fn()
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Failed to rewind the output: %s.", err)
+	}
+	buf, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("Failed to read the output: %s.", err)
+	}
+	return string(buf)
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  func(*library.Library, []string) error
+		args []string
+		want string
+		err  error // errFailed or nil, any error if set otherwise.
+	}{
+		{
+			name: "list",
+			cmd:  list,
+			want: "zork===47cecba4085e9c137095663ff6402dcd\ncopy\nliar===47cecba4085e9c137095663ff6402dcd\nbroken\n",
+		},
+		{
+			name: "info",
+			cmd:  info,
+			args: []string{"zork", "broken.s"},
+			want: `file:    srcs/zork===47cecba4085e9c137095663ff6402dcd.s
+name:    zork
+comment: test
+size:    8
+md5:     47cecba4085e9c137095663ff6402dcd
+
+file:    srcs/broken.s
+error:   srcs/broken.s: missing program name (and 1 more error)
+`,
+		},
+		{name: "info unknown", cmd: info, args: []string{"nope"}, err: errors.New("not found")},
+		{name: "extract", cmd: extract, args: []string{"liar"}, want: string(champion("liar", 2))},
+		{name: "extract several", cmd: extract, args: []string{"liar", "copy"}, err: errors.New("several")},
+		{
+			name: "compile",
+			cmd:  compileAll,
+			want: "FAIL srcs/broken.s: srcs/broken.s: missing program name (and 1 more error)\n3/4 champions compiled.\n",
+			err:  errFailed,
+		},
+		{
+			name: "dedupe",
+			cmd:  dedupe,
+			want: `HASH srcs/liar===47cecba4085e9c137095663ff6402dcd.s: program hash is 5dbc5e3f94d7b8009915aadb15fa4829
+FAIL srcs/broken.s: srcs/broken.s: missing program name (and 1 more error)
+DUP 47cecba4085e9c137095663ff6402dcd: zork===47cecba4085e9c137095663ff6402dcd, copy
+2 unique programs, 1 duplicated.
+`,
+			err: errFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			out := captureStdout(t, func() { err = tt.cmd(testLibrary(), tt.args) })
+			switch {
+			case tt.err == nil && err != nil:
+				t.Fatalf("Failed to run: %s.", err)
+			case tt.err == errFailed && !errors.Is(err, errFailed):
+				t.Fatalf("Unexpected error: %v, expected %s.", err, errFailed)
+			case tt.err != nil && err == nil:
+				t.Fatal("Expected an error.")
+			}
+			if out != tt.want {
+				t.Fatalf("Unexpected output:\n%s\nexpected:\n%s", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := extract(testLibrary(), []string{"-o", dir, "copy", "liar"}); err != nil {
+		t.Fatalf("Failed to extract: %s.", err)
+	}
+	for path, want := range map[string][]byte{
+		"copy.s": champion("zork", 1),
+		"liar===47cecba4085e9c137095663ff6402dcd.s": champion("liar", 2),
+	} {
+		buf, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Fatalf("Failed to read the extracted source: %s.", err)
+		}
+		if string(buf) != string(want) {
+			t.Fatalf("Unexpected source of %q:\n%s\nexpected:\n%s", path, buf, want)
+		}
+	}
+}
@@ -0,0 +1,445 @@
+// Command cwdb is a gdb-style debugger for champions, reading commands from stdin.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.creack.net/corewar/asm"
+	"go.creack.net/corewar/asm/parser"
+	"go.creack.net/corewar/cli"
+	"go.creack.net/corewar/op"
+	"go.creack.net/corewar/vm"
+)
+
+const help = `Commands:
+  break <addr>                 Stop when a process moves to addr.
+  break <opcode> [player]      Stop when a process (of player) executes the opcode, e.g. break fork 2.
+  break cycle <n>              Stop when reaching cycle n.
+  watch <addr> [size]          Stop when a process writes to [addr, addr+size), size defaults to 4.
+  delete <id>                  Remove a breakpoint or watchpoint.
+  step [n]                     Play n rounds, defaults to 1.
+  next-cycle [n]               Play until the next cycle, or n cycles.
+  continue                     Play until a breakpoint or the end of the game.
+  info processes               List the processes.
+  info registers <pid>         Show the registers of the process.
+  info breakpoints             List the breakpoints and watchpoints.
+  x/<n> <addr>                 Examine n bytes of memory, defaults to 16.
+  disas <addr> [n]             Disassemble n instructions, defaults to 10.
+  print <cycle|ctd|lives>      Show the cycle, cycles to die, or live counts.
+  help                         Show this help.
+  quit                         Exit.
+Addresses and numbers can be decimal or hexadecimal (0x).`
+
+// debugger runs the commands against the game.
+type debugger struct {
+	cw  *vm.Corewar
+	out io.Writer
+
+	sourceMaps map[int]*asm.SourceMap // By player number, when known.
+}
+
+// source returns the source location and line the process is at, if known.
+func (d *debugger) source(p *vm.Process) string {
+	m := d.sourceMaps[p.Player.Number]
+	if m == nil {
+		return ""
+	}
+	offset, ok := d.cw.LoadOffset(p)
+	if !ok {
+		return ""
+	}
+	e, ok := m.Lookup(offset)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d: %s", m.File, e.Line, e.Text)
+}
+
+// observer prints the events worth knowing while debugging.
+type observer struct {
+	vm.NopObserver
+	out io.Writer
+}
+
+func (o observer) OnDisplay(e vm.DisplayEvent) {
+	fmt.Fprintf(o.out, "[aff] process %d: %c\n", e.Process.ID, e.Char)
+}
+
+func (o observer) OnGameOver(e vm.GameOverEvent) {
+	fmt.Fprintf(o.out, "Game over (%s), player %d (%s) won.\n", e.Reason, e.Winner.Number, e.Winner.Name)
+}
+
+// parseNumber parses a decimal or hexadecimal number.
+func parseNumber(s string) (int, error) {
+	n, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return int(n), nil
+}
+
+// lookupOpCode returns the opcode with the given name.
+func lookupOpCode(name string) (op.OpCode, bool) {
+	for _, elem := range op.OpCodeTable {
+		if elem.Name == name && elem.Code != 0 {
+			return elem, true
+		}
+	}
+	return op.OpCode{}, false
+}
+
+// play runs the game and reports why it stopped.
+func (d *debugger) play(f func() error) error {
+	if d.cw.EndReason != vm.EndNone {
+		return errors.New("the game is over")
+	}
+	err := f()
+	var breakErr *vm.BreakError
+	switch {
+	case err == nil:
+	case errors.Is(err, io.EOF):
+		return nil // Reported by the observer.
+	case errors.As(err, &breakErr):
+		for _, elem := range breakErr.Hits {
+			fmt.Fprintf(d.out, "%s.\n", elem)
+		}
+	default:
+		return err
+	}
+	fmt.Fprintf(d.out, "Cycle %d.\n", d.cw.Cycle)
+	return nil
+}
+
+func (d *debugger) cmdBreak(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: break <addr> | break <opcode> [player] | break cycle <n>")
+	}
+	var b vm.Breakpoint
+	if args[0] == "cycle" {
+		if len(args) != 2 {
+			return errors.New("usage: break cycle <n>")
+		}
+		n, err := parseNumber(args[1])
+		if err != nil {
+			return err
+		}
+		b = vm.Breakpoint{Kind: vm.BreakCycle, Cycle: n}
+	} else if code, ok := lookupOpCode(args[0]); ok {
+		b = vm.Breakpoint{Kind: vm.BreakOpcode, OpCode: code.Code}
+		if len(args) > 1 {
+			n, err := parseNumber(args[1])
+			if err != nil {
+				return err
+			}
+			b.Player = n
+		}
+	} else {
+		n, err := parseNumber(args[0])
+		if err != nil {
+			return err
+		}
+		b = vm.Breakpoint{Kind: vm.BreakAddr, Addr: uint32(n)}
+	}
+	d.cw.AddBreakpoint(b)
+	bps := d.cw.Breakpoints()
+	fmt.Fprintf(d.out, "Added %s.\n", bps[len(bps)-1])
+	return nil
+}
+
+func (d *debugger) cmdWatch(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: watch <addr> [size]")
+	}
+	addr, err := parseNumber(args[0])
+	if err != nil {
+		return err
+	}
+	size := 4
+	if len(args) == 2 {
+		if size, err = parseNumber(args[1]); err != nil {
+			return err
+		}
+	}
+	if size <= 0 {
+		return fmt.Errorf("invalid size %d", size)
+	}
+	d.cw.AddBreakpoint(vm.Breakpoint{Kind: vm.BreakWatch, Addr: uint32(addr), Size: size})
+	bps := d.cw.Breakpoints()
+	fmt.Fprintf(d.out, "Added %s.\n", bps[len(bps)-1])
+	return nil
+}
+
+func (d *debugger) cmdDelete(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: delete <id>")
+	}
+	id, err := parseNumber(args[0])
+	if err != nil {
+		return err
+	}
+	if !d.cw.RemoveBreakpoint(id) {
+		return fmt.Errorf("no breakpoint %d", id)
+	}
+	return nil
+}
+
+func (d *debugger) cmdStep(args []string) error {
+	n := 1
+	if len(args) > 0 {
+		var err error
+		if n, err = parseNumber(args[0]); err != nil {
+			return err
+		}
+	}
+	return d.play(func() error {
+		for range n {
+			if err := d.cw.Round(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (d *debugger) cmdNextCycle(args []string) error {
+	n := 1
+	if len(args) > 0 {
+		var err error
+		if n, err = parseNumber(args[0]); err != nil {
+			return err
+		}
+	}
+	return d.play(func() error { return d.cw.RunUntil(context.Background(), d.cw.Cycle+n) })
+}
+
+func (d *debugger) cmdContinue([]string) error {
+	return d.play(func() error { return d.cw.RunUntil(context.Background(), -1) })
+}
+
+func (d *debugger) cmdInfo(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: info <processes|registers <pid>|breakpoints>")
+	}
+	switch args[0] {
+	case "processes":
+		fmt.Fprintf(d.out, "%5s %6s %6s %-6s %5s %5s %s\n", "pid", "player", "pc", "op", "wait", "carry", "source")
+		for _, p := range d.cw.Processes {
+			name := "-"
+			if p.CurInstruction != nil {
+				name = p.CurInstruction.OpCode.Name
+			}
+			fmt.Fprintf(d.out, "%5d %6d 0x%04x %-6s %5d %5t %s\n", p.ID, p.Player.Number, p.PC, name, p.WaitCycles, p.Carry, d.source(p))
+		}
+	case "registers":
+		if len(args) != 2 {
+			return errors.New("usage: info registers <pid>")
+		}
+		pid, err := parseNumber(args[1])
+		if err != nil {
+			return err
+		}
+		for _, p := range d.cw.Processes {
+			if p.ID != pid {
+				continue
+			}
+			for i, r := range p.Registers {
+				fmt.Fprintf(d.out, "r%-2d 0x%08x %d\n", i+1, r, int32(r))
+			}
+			fmt.Fprintf(d.out, "pc  0x%04x\ncarry %t\n", p.PC, p.Carry)
+			return nil
+		}
+		return fmt.Errorf("no process %d", pid)
+	case "breakpoints":
+		for _, b := range d.cw.Breakpoints() {
+			fmt.Fprintf(d.out, "%s\n", b)
+		}
+	default:
+		return fmt.Errorf("unknown info %q", args[0])
+	}
+	return nil
+}
+
+func (d *debugger) cmdExamine(count string, args []string) error {
+	n := 16
+	if count != "" {
+		var err error
+		if n, err = parseNumber(count); err != nil {
+			return err
+		}
+	}
+	if len(args) != 1 {
+		return errors.New("usage: x/<n> <addr>")
+	}
+	addr, err := parseNumber(args[0])
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, max(n, 0))
+	d.cw.Ram.Read(uint32(addr), buf)
+	for i, b := range buf {
+		if i%16 == 0 {
+			if i != 0 {
+				fmt.Fprintln(d.out)
+			}
+			fmt.Fprintf(d.out, "0x%04x:", (uint32(addr)+uint32(i))%uint32(len(d.cw.Ram)))
+		}
+		fmt.Fprintf(d.out, " %02x", b)
+	}
+	fmt.Fprintln(d.out)
+	return nil
+}
+
+func (d *debugger) cmdDisas(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: disas <addr> [n]")
+	}
+	addr, err := parseNumber(args[0])
+	if err != nil {
+		return err
+	}
+	n := 10
+	if len(args) == 2 {
+		if n, err = parseNumber(args[1]); err != nil {
+			return err
+		}
+	}
+	buf := make([]byte, 4+4*op.MaxArgsNumber)
+	pc := uint32(addr) % uint32(len(d.cw.Ram))
+	for range n {
+		d.cw.Ram.Read(pc, buf)
+		ins, size, err := parser.DecodeNextInstruction(buf)
+		if err != nil {
+			fmt.Fprintf(d.out, "0x%04x: .code %02x\n", pc, buf[0])
+			size = 1
+		} else {
+			fmt.Fprintf(d.out, "0x%04x: %s\n", pc, ins)
+		}
+		pc = (pc + uint32(size)) % uint32(len(d.cw.Ram))
+	}
+	return nil
+}
+
+func (d *debugger) cmdPrint(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: print <cycle|ctd|lives>")
+	}
+	switch args[0] {
+	case "cycle":
+		fmt.Fprintf(d.out, "%d\n", d.cw.Cycle)
+	case "ctd":
+		fmt.Fprintf(d.out, "%d (%d left, %d checks)\n", d.cw.CyclesToDie, d.cw.CurCyclesToDie, d.cw.Checks)
+	case "lives":
+		fmt.Fprintf(d.out, "%d live calls since the last check\n", d.cw.LiveCalls)
+		for _, p := range d.cw.Players {
+			fmt.Fprintf(d.out, "player %d (%s): %d current, %d total, last at cycle %d, dead: %t\n",
+				p.Number, p.Name, p.CurrentLives, p.TotalLives, p.LastLiveCycle, p.Dead)
+		}
+	default:
+		return fmt.Errorf("unknown value %q", args[0])
+	}
+	return nil
+}
+
+// exec runs a command line. Returns true to quit.
+func (d *debugger) exec(line string) (bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false, nil
+	}
+	cmd, args := fields[0], fields[1:]
+	if count, ok := strings.CutPrefix(cmd, "x/"); ok || cmd == "x" {
+		return false, d.cmdExamine(count, args)
+	}
+	switch cmd {
+	case "break", "b":
+		return false, d.cmdBreak(args)
+	case "watch":
+		return false, d.cmdWatch(args)
+	case "delete":
+		return false, d.cmdDelete(args)
+	case "step", "s":
+		return false, d.cmdStep(args)
+	case "next-cycle", "n":
+		return false, d.cmdNextCycle(args)
+	case "continue", "c":
+		return false, d.cmdContinue(args)
+	case "info", "i":
+		return false, d.cmdInfo(args)
+	case "disas":
+		return false, d.cmdDisas(args)
+	case "print", "p":
+		return false, d.cmdPrint(args)
+	case "help", "h":
+		fmt.Fprintln(d.out, help)
+		return false, nil
+	case "quit", "q":
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown command %q, try help", cmd)
+}
+
+// newGame creates the game to debug, from the players or from
+// the replay given with -replay, with its players disassembled.
+func newGame(cfg vm.Config, players []*cli.Player, opts cli.Options, obs vm.Observer) (*vm.Corewar, []*cli.Player, error) {
+	if opts.Replay == "" {
+		cfg.Observer = obs
+		cw, err := vm.NewCorewar(cfg)
+		return cw, players, err
+	}
+	r, players, err := cli.LoadReplay(opts.Replay)
+	if err != nil {
+		return nil, nil, err
+	}
+	r.Config.Observer = obs
+	cw, err := r.NewCorewar()
+	return cw, players, err
+}
+
+func main() {
+	log.SetFlags(0)
+
+	cfg, players, opts, err := cli.ParseConfig()
+	if err != nil {
+		log.Fatalf("Failed to parse CLI config: %s.", err)
+	}
+	if opts.Snapshot != "" {
+		log.Fatalf("Snapshots can't be resumed in the debugger, use vm-viewer -load.")
+	}
+	cw, players, err := newGame(cfg, players, opts, observer{out: os.Stdout})
+	if err != nil {
+		log.Fatalf("Failed to create VM: %s.", err)
+	}
+
+	d := &debugger{cw: cw, out: os.Stdout, sourceMaps: map[int]*asm.SourceMap{}}
+	for _, p := range players {
+		if p.SourceMap != nil {
+			d.sourceMaps[p.Number] = p.SourceMap
+		}
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Fprint(d.out, "(cwdb) ")
+		if !scanner.Scan() {
+			fmt.Fprintln(d.out)
+			break
+		}
+		quit, err := d.exec(scanner.Text())
+		if err != nil {
+			fmt.Fprintf(d.out, "Error: %s.\n", err)
+		}
+		if quit {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("Failed to read input: %s.", err)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.creack.net/corewar/asm"
+	"go.creack.net/corewar/asm/parser"
+	"go.creack.net/corewar/cli"
+	"go.creack.net/corewar/op"
+	"go.creack.net/corewar/vm"
+)
+
+const quitter = `.name "quitter"
+.comment "lives once"
+
+	sti	r1, %:live, %1
+	ld	%0, r2
+live:	live	%1
+wait:	zjmp	%:wait
+`
+
+// testConfig returns the config of a game between two quitters, and the
+// parsed quitter.
+func testConfig(t *testing.T) (vm.Config, *parser.Program) {
+	t.Helper()
+	buf, pr, err := asm.Compile("quitter.s", quitter, parser.WarningOptions{})
+	if err != nil {
+		t.Fatalf("Failed to compile: %s.", err)
+	}
+	return vm.Config{
+		MemSize:     op.MemSize,
+		IdxMod:      op.IdxMod,
+		CyclesToDie: op.CyclesToDie,
+		CycleDelta:  op.CycleDelta,
+		NumLives:    op.NumLives,
+		MaxChecks:   op.MaxChecks,
+		Players:     []vm.PlayerConfig{{Number: 1, Data: buf}, {Number: 2, Data: buf}},
+	}, pr
+}
+
+// newDebugger returns a debugger on a game between two quitters,
+// with the source map of the first one.
+func newDebugger(t *testing.T, out *strings.Builder) *debugger {
+	t.Helper()
+	cfg, pr := testConfig(t)
+	cfg.Observer = observer{out: out}
+	cw, err := vm.NewCorewar(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create the game: %s.", err)
+	}
+	return &debugger{cw: cw, out: out, sourceMaps: map[int]*asm.SourceMap{1: asm.NewSourceMap("quitter.s", quitter, pr)}}
+}
+
+// session runs the command lines and returns the transcript.
+func session(t *testing.T, lines string) string {
+	t.Helper()
+	var out strings.Builder
+	d := newDebugger(t, &out)
+	for _, line := range strings.Split(strings.TrimSpace(lines), "\n") {
+		out.WriteString("(cwdb) " + line + "\n")
+		quit, err := d.exec(line)
+		if err != nil {
+			out.WriteString("Error: " + err.Error() + ".\n")
+		}
+		if quit {
+			break
+		}
+	}
+	return out.String()
+}
+
+func TestSession(t *testing.T) {
+	got := session(t, `
+break 0x000e
+watch 0x080f
+info breakpoints
+continue
+continue
+print lives
+info processes
+info registers 9
+x/8 0x0800
+disas 0x0e 2
+delete 1
+delete 2
+break cycle 100
+n 10
+c
+print cycle
+continue
+step
+bogus
+quit
+print cycle
+`)
+	const want = `(cwdb) break 0x000e
+Added breakpoint 1 at 0x000e.
+(cwdb) watch 0x080f
+Added watchpoint 2 on 4 bytes at 0x080f.
+(cwdb) info breakpoints
+breakpoint 1 at 0x000e
+watchpoint 2 on 4 bytes at 0x080f
+(cwdb) continue
+cycle 25: watchpoint 2 on 4 bytes at 0x080f hit by process 2 (player 2) at 0x080f.
+Cycle 26.
+(cwdb) continue
+cycle 30: breakpoint 1 at 0x000e hit by process 1 (player 1) at 0x000e.
+Cycle 31.
+(cwdb) print lives
+0 live calls since the last check
+player 1 (quitter): 0 current, 0 total, last at cycle 0, dead: false
+player 2 (quitter): 0 current, 0 total, last at cycle 0, dead: false
+(cwdb) info processes
+  pid player     pc op      wait carry source
+    1      1 0x000e live       9  true quitter.s:6: live:	live	%1
+    2      2 0x080e live       9  true 
+(cwdb) info registers 9
+Error: no process 9.
+(cwdb) x/8 0x0800
+0x0800: 0b 68 01 00 0e 00 01 02
+(cwdb) disas 0x0e 2
+0x000e: <live (%1)>
+0x0013: <zjmp (%0)>
+(cwdb) delete 1
+(cwdb) delete 2
+(cwdb) break cycle 100
+Added cycle breakpoint 3 at cycle 100.
+(cwdb) n 10
+Cycle 41.
+(cwdb) c
+cycle 100: cycle breakpoint 3 at cycle 100.
+Cycle 100.
+(cwdb) print cycle
+100
+(cwdb) continue
+Game over (no process left), player 2 (quitter) won.
+(cwdb) step
+Error: the game is over.
+(cwdb) bogus
+Error: unknown command "bogus", try help.
+(cwdb) quit
+`
+	if got != want {
+		t.Fatalf("Unexpected session:\n%s\nexpected:\n%s", got, want)
+	}
+}
+
+func TestNewGameReplay(t *testing.T) {
+	cfg, _ := testConfig(t)
+	r, err := vm.Record(context.Background(), cfg, vm.DefaultReplayInterval)
+	if err != nil {
+		t.Fatalf("Failed to record: %s.", err)
+	}
+	path := filepath.Join(t.TempDir(), "game.json")
+	if err := cli.WriteReplay(path, r); err != nil {
+		t.Fatalf("Failed to write the replay: %s.", err)
+	}
+
+	var out strings.Builder
+	cw, players, err := newGame(vm.Config{}, nil, cli.Options{DumpCycle: -1, Replay: path}, observer{out: &out})
+	if err != nil {
+		t.Fatalf("Failed to create the game: %s.", err)
+	}
+	if len(players) != 2 || players[1].Number != 2 || players[1].ShortName != "quitter" {
+		t.Fatalf("Unexpected players: %+v.", players)
+	}
+	if err := cw.RunUntil(context.Background(), -1); !errors.Is(err, io.EOF) {
+		t.Fatalf("Failed to run the replayed game: %v.", err)
+	}
+	if cw.Winner == nil || cw.Winner.Number != 2 || !strings.Contains(out.String(), "player 2 (quitter) won") {
+		t.Fatalf("Unexpected end of the replayed game: %+v\n%s", cw.Winner, out.String())
+	}
+
+	if _, _, err := newGame(vm.Config{}, nil, cli.Options{DumpCycle: -1, Replay: filepath.Join(t.TempDir(), "missing.json")}, observer{out: &out}); err == nil {
+		t.Fatal("Debugging a missing replay.")
+	}
+}
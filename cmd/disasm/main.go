@@ -8,12 +8,24 @@ import (
 	"os"
 	"strings"
 
+	"go.creack.net/corewar/asm/parser"
 	"go.creack.net/corewar/disasm"
 )
 
 func main() {
-	strict := flag.Bool("strict", false, "strict mode")
+	werror := flag.Bool("Werror", false, "treat the warnings as errors")
+	wno := flag.String("Wno", "", "comma separated warning codes to ignore, e.g. W003,W004")
+	verify := flag.Bool("verify", false, "check that the disassembly assembles back to the same bytes instead of printing it")
+	verifyCorpus := flag.Bool("verify-corpus", false, "verify every champion of the embedded corpus and print a report")
 	flag.Parse()
+	warnOpts := parser.WarningOptions{Error: *werror}
+	warnOpts.Disable(*wno)
+	if *verifyCorpus {
+		if !runVerifyCorpus(warnOpts) {
+			os.Exit(1)
+		}
+		return
+	}
 	f := flag.Arg(0)
 	if f == "" {
 		tmp := strings.Split(os.Args[0], "/")
@@ -27,12 +39,38 @@ func main() {
 		log.Fatalf("failed to read file %q: %s", f, err)
 		return
 	}
-	prog, err := disasm.Disam(f, binData, *strict)
+	if *verify {
+		if err := disasm.Verify(f, binData, warnOpts); err != nil {
+			log.Fatalf("Failed to verify %q: %s.", f, err)
+		}
+		fmt.Printf("%s: ok\n", f)
+		return
+	}
+	prog, err := disasm.Disam(f, binData, warnOpts)
 	if err != nil {
 		log.Fatalf("failed to disassemble %q: %s", f, err)
 		return
 	}
-	for _, elem := range prog.Nodes {
-		fmt.Printf("%s\n", elem.PrettyPrint(prog.Nodes))
+	for _, d := range prog.Warnings() {
+		fmt.Fprintf(os.Stderr, "%s\n", d)
+	}
+	fmt.Print(parser.Format(prog.Nodes))
+}
+
+// runVerifyCorpus prints the champions of the corpus failing the
+// round trip and a summary. Returns false if any failed.
+func runVerifyCorpus(warnOpts parser.WarningOptions) bool {
+	results, err := disasm.VerifyCorpus(warnOpts)
+	if err != nil {
+		log.Fatalf("Failed to verify the corpus: %s.", err)
+	}
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Printf("FAIL %s: %s\n", r.Name, r.Err)
+		}
 	}
+	fmt.Printf("%d/%d champions assemble back to the same bytes.\n", len(results)-failed, len(results))
+	return failed == 0
 }
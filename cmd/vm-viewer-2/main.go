@@ -1,8 +1,6 @@
 package main
 
 import (
-	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"image/color"
@@ -378,7 +376,9 @@ var fontFace = text.NewGoXFace(bitmapfont.Face)
 // }
 
 type RamEntry struct {
-	vm.RamEntry
+	Value byte
+	vm.Cell
+	pc bool // Set when a process' PC is on the entry.
 	//image         *ebiten.Image // TODO: text.
 	color         *color.RGBA
 	idx           int
@@ -400,7 +400,7 @@ func (re RamEntry) Draw(screen *ebiten.Image) {
 
 	if true {
 		var img *ebiten.Image
-		if re.RamEntry.Process != nil && int(re.RamEntry.Process.PC) == re.idx {
+		if re.pc {
 			img = re.pcBG
 			col = &color.RGBA{A: 0xFF}
 		} else {
@@ -416,7 +416,7 @@ func (re RamEntry) Draw(screen *ebiten.Image) {
 
 	if re.hovered {
 		col = &color.RGBA{R: 0xFF, A: 0xFF}
-	} else if re.Process == nil && re.Value == 0 {
+	} else if re.Player == 0 && re.Value == 0 {
 		col = &color.RGBA{R: 0x69, G: 0x69, B: 0x69, A: 0xFF}
 	}
 
@@ -441,6 +441,7 @@ type Game struct {
 
 	ramMu sync.RWMutex
 	ram   []*RamEntry
+	pcs   []int // Indexes of the entries flagged as PC.
 
 	lastCycle time.Time
 	ended     bool
@@ -465,9 +466,7 @@ func NewGame() *Game {
 	const width = 64
 	for i, elem := range ram {
 		ramEntries = append(ramEntries, &RamEntry{
-			RamEntry: vm.RamEntry{
-				Value: elem,
-			},
+			Value:  elem,
 			color:  &color.RGBA{R: 0xFF, A: 0xFF},
 			idx:    i,
 			x:      (i % width) * int(3*charWidth),
@@ -562,6 +561,52 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeigh
 	return initialScreenWidth, initialScreenHeight
 }
 
+// observer updates the game from the VM events.
+type observer struct {
+	vm.NopObserver
+	g *Game
+}
+
+func (o *observer) OnRound(e vm.RoundEvent) {
+	o.g.ramMu.Lock()
+	defer o.g.ramMu.Unlock()
+	for _, elem := range e.Changes {
+		for i := range elem.Size {
+			idx := (int(elem.Addr) + i) % len(o.g.cw.Ram)
+			o.g.ram[idx].Value = o.g.cw.Ram[idx]
+			o.g.ram[idx].Cell = o.g.cw.Owners[idx]
+			if elem.Process != nil {
+				r, g, b := colors[elem.Process.Player.Number%len(colors)].RGB()
+				o.g.ram[idx].color = &color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 0xFF}
+			}
+		}
+	}
+
+	// Move the PC markers.
+	for _, idx := range o.g.pcs {
+		o.g.ram[idx].pc = false
+	}
+	o.g.pcs = o.g.pcs[:0]
+	for _, p := range o.g.cw.Processes {
+		o.g.ram[p.PC].pc = true
+		o.g.pcs = append(o.g.pcs, int(p.PC))
+	}
+}
+
+func (o *observer) OnLive(e vm.LiveEvent) {
+	if e.Player != nil {
+		log.Printf("[Live] Player %d (%s) is alive", e.Player.Number, e.Player.Name)
+	}
+}
+
+func (o *observer) OnDeath(e vm.DeathEvent) {
+	log.Printf("[Dead] Player %d (%s) died", e.Player.Number, e.Player.Name)
+}
+
+func (o *observer) OnGameOver(e vm.GameOverEvent) {
+	log.Printf("[Game Over] %s, player %d (%s) won", e.Reason, e.Winner.Number, e.Winner.Name)
+}
+
 var curColor = 0
 
 var colors = []tcell.Color{
@@ -596,46 +641,20 @@ func main() {
 	ebiten.SetScreenClearedEveryFrame(false)
 	ebiten.SetVsyncEnabled(true)
 
-	cfg, _, err := cli.ParseConfig()
+	cfg, _, _, err := cli.ParseConfig()
 	if err != nil {
 		log.Fatalf("Failed to parse cli config: %s.", err)
 	}
 
-	cw := vm.NewCorewar(cfg)
+	cfg.Observer = &observer{g: game}
+	cfg.TrackOwnership = true
+	cw, err := vm.NewCorewar(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create VM: %s.", err)
+	}
 	game.cw = cw
 	game.lastCycle = time.Now()
 
-	ctx := context.Background()
-	go func() {
-	loop:
-		select {
-		case <-ctx.Done():
-			return
-		case msg := <-cw.Messages:
-			switch msg.Type {
-			case vm.MsgDump:
-				var ram vm.Ram
-				if err := json.Unmarshal([]byte(msg.Message), &ram); err != nil {
-					log.Fatalf("Failed to unmarshal ram dump: %s.", err)
-				}
-
-				game.ramMu.Lock()
-				for i, elem := range ram {
-					game.ram[i].RamEntry = elem
-					if elem.Process != nil {
-						r, g, b := colors[elem.Process.ID%len(colors)].RGB()
-						game.ram[i].color = &color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 0xFF}
-					}
-				}
-				game.ramMu.Unlock()
-
-			default:
-				log.Printf("[%s] Message: %s", msg.Type, msg.Message)
-			}
-		}
-		goto loop
-	}()
-
 	// if err := cw.Round(); err != nil {
 	// 	log.Fatalf("Failed to execute first round: %s.", err)
 	// }
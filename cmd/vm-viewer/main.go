@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
 	"runtime/debug"
 	"slices"
 	"strings"
@@ -16,6 +17,8 @@ import (
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 
+	"go.creack.net/corewar/asm"
+	"go.creack.net/corewar/asm/parser"
 	"go.creack.net/corewar/cli"
 	"go.creack.net/corewar/op"
 	"go.creack.net/corewar/vm"
@@ -240,7 +243,10 @@ type Game struct {
 	playerListView  tview.Primitive
 	logsView        *tview.TextView
 
-	cw *vm.Corewar
+	cw     *vm.Corewar
+	replay *vm.Replay // Set when playing a replay, verified along the way.
+
+	sourceMaps map[int]*asm.SourceMap // By player number, when known.
 
 	paused   bool
 	pausedMu sync.Mutex
@@ -248,6 +254,12 @@ type Game struct {
 	nextStep   bool
 	nextStepMu sync.Mutex
 
+	save   bool // Save a snapshot with the next update.
+	saveMu sync.Mutex
+
+	stepBack   bool // Go back one cycle with the next update.
+	stepBackMu sync.Mutex
+
 	ctx    context.Context
 	cancel context.CancelFunc
 }
@@ -283,6 +295,16 @@ func (g *Game) Init() {
 			g.nextStep = true
 			g.nextStepMu.Unlock()
 			return nil
+		case 'b':
+			g.stepBackMu.Lock()
+			g.stepBack = true
+			g.stepBackMu.Unlock()
+			return nil
+		case 's':
+			g.saveMu.Lock()
+			g.save = true
+			g.saveMu.Unlock()
+			return nil
 		case ' ':
 			if curPage == "main" {
 				g.pausedMu.Lock()
@@ -303,42 +325,65 @@ func (g *Game) Init() {
 		return event
 	}
 	g.root.SetInputCapture(f)
-	go func() {
-	loop:
-		select {
-		case msg := <-g.cw.Messages:
-			g.app.QueueUpdateDraw(func() {
-				if msg.Type == vm.MsgClear {
-					g.logsView.Clear()
-					return
-				}
-				if msg.Type == vm.MsgPause {
-					g.pausedMu.Lock()
-					g.paused = true
-					g.pausedMu.Unlock()
-					return
-				}
-				if msg.Type == vm.MsgDump {
-					return
-				}
-				// NOTE: Seems like there is a bug with tview, we can't reset the color to default
-				// with [:] or [:::], so we use tcell default.
-				colorCode := "[" + tcell.ColorDefault.String() + ":::]"
-				if msg.Process != nil {
-					colorCode = "[" + colors[msg.Process.ID%len(colors)].String() + ":::]"
-				}
-				if msg.Process != nil {
-					fmt.Fprintf(g.logsView, "%s[%d] %s[:::]\n", colorCode, msg.Process.ID, strings.TrimSuffix(msg.Message, "\n"))
-				} else {
-					fmt.Fprintf(g.logsView, "%s%s[:::]\n", colorCode, strings.TrimSuffix(msg.Message, "\n"))
-				}
-			})
-			g.app.Draw()
-		case <-g.ctx.Done():
+}
+
+// log appends the given message to the logs view.
+// Safe to call from any goroutine.
+func (g *Game) log(p *vm.Process, msg string) {
+	g.app.QueueUpdateDraw(func() {
+		// NOTE: Seems like there is a bug with tview, we can't reset the color to default
+		// with [:] or [:::], so we use tcell default.
+		if p == nil {
+			fmt.Fprintf(g.logsView, "[%s:::]%s[:::]\n", tcell.ColorDefault, msg)
 			return
 		}
-		goto loop
-	}()
+		fmt.Fprintf(g.logsView, "[%s:::][%d] %s[:::]\n", colors[p.ID%len(colors)], p.ID, msg)
+	})
+}
+
+// pause pauses the game. Safe to call from any goroutine.
+func (g *Game) pause() {
+	g.pausedMu.Lock()
+	g.paused = true
+	g.pausedMu.Unlock()
+}
+
+// observer forwards the VM events to the game logs.
+type observer struct {
+	vm.NopObserver
+	g *Game
+}
+
+func (o observer) OnExec(e vm.ExecEvent) {
+	o.g.log(e.Process, fmt.Sprintf("Executed %s", e.Instruction))
+}
+
+func (o observer) OnLive(e vm.LiveEvent) {
+	if e.Player == nil {
+		o.g.log(e.Process, fmt.Sprintf("Missed 'live' from %d (%s)", e.Process.Player.Number, e.Process.Player.Name))
+		return
+	}
+	o.g.log(e.Process, fmt.Sprintf("Player %d (%s) is alive", e.Player.Number, e.Player.Name))
+}
+
+func (o observer) OnWrite(e vm.WriteEvent) {
+	o.g.log(e.Process, fmt.Sprintf("Write 0x%08x at %d", e.Value, e.Addr))
+}
+
+func (o observer) OnFork(e vm.ForkEvent) {
+	o.g.log(e.Parent, fmt.Sprintf("Forking process %d to %d", e.Parent.ID, e.Child.ID))
+}
+
+func (o observer) OnDisplay(e vm.DisplayEvent) {
+	o.g.log(e.Process, fmt.Sprintf("%c", e.Char))
+}
+
+func (o observer) OnDeath(e vm.DeathEvent) {
+	o.g.log(nil, fmt.Sprintf("Player %d (%s) died", e.Player.Number, e.Player.Name))
+}
+
+func (o observer) OnGameOver(e vm.GameOverEvent) {
+	o.g.log(nil, fmt.Sprintf("Game over (%s), player %d (%s) won", e.Reason, e.Winner.Number, e.Winner.Name))
 }
 
 func (g *Game) Update() error {
@@ -356,11 +401,39 @@ func (g *Game) Update() error {
 		}
 		return false
 	}
+	shouldSave := func() bool {
+		g.saveMu.Lock()
+		defer g.saveMu.Unlock()
+		if g.save {
+			g.save = false
+			return true
+		}
+		return false
+	}
+	if shouldSave() {
+		g.saveSnapshot()
+	}
+	shouldStepBack := func() bool {
+		g.stepBackMu.Lock()
+		defer g.stepBackMu.Unlock()
+		if g.stepBack {
+			g.stepBack = false
+			return true
+		}
+		return false
+	}
+	if shouldStepBack() {
+		g.pause()
+		if err := g.cw.StepBack(1); err != nil {
+			g.log(nil, fmt.Sprintf("Failed to step back: %s", err))
+		}
+		return nil
+	}
 	if !forceNextStep() && isPaused() {
 		return nil
 	}
 
-	if err := g.cw.Round(); err != nil {
+	if err := g.round(); err != nil {
 		return fmt.Errorf("failed to execute instruction: %w", err)
 	}
 	g.Draw()
@@ -368,6 +441,40 @@ func (g *Game) Update() error {
 	return nil
 }
 
+// round plays the next round, verifying it when playing a replay.
+// Breakpoints and replay mismatches are logged and pause the game.
+func (g *Game) round() error {
+	var err error
+	if g.replay == nil {
+		err = g.cw.Round()
+	} else {
+		err = g.replay.Step(g.ctx, g.cw)
+	}
+	var breakErr *vm.BreakError
+	if errors.As(err, &breakErr) || errors.Is(err, vm.ErrReplayMismatch) {
+		g.log(nil, err.Error())
+		g.pause()
+		return nil
+	}
+	return err
+}
+
+// saveSnapshot writes the current state of the game to corewar-<cycle>.snap,
+// to be resumed later with -load.
+func (g *Game) saveSnapshot() {
+	data, err := g.cw.MarshalBinary()
+	if err != nil {
+		g.log(nil, fmt.Sprintf("Failed to snapshot: %s", err))
+		return
+	}
+	name := fmt.Sprintf("corewar-%d.snap", g.cw.Cycle)
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		g.log(nil, fmt.Sprintf("Failed to save snapshot: %s", err))
+		return
+	}
+	g.log(nil, fmt.Sprintf("Snapshot saved to %s", name))
+}
+
 func (g *Game) drawProcessList() {
 	g.processListView.SetTitle(fmt.Sprintf("Processes (%d)", len(g.cw.Processes)))
 	g.processListView.Clear()
@@ -379,6 +486,7 @@ func (g *Game) drawProcessList() {
 		"wait",
 		"registers",
 		"carry",
+		"source",
 	} {
 		cell := tview.NewTableCell(elem).
 			SetAttributes(tcell.AttrBold).
@@ -411,6 +519,7 @@ func (g *Game) drawProcessList() {
 			elem.WaitCycles,
 			dumpRegisters(elem.Registers[:]),
 			elem.Carry,
+			g.source(elem),
 		} {
 			cell := tview.NewTableCell(fmt.Sprint(content)).SetAlign(tview.AlignRight)
 			cell.SetTextColor(colors[elem.ID%len(colors)])
@@ -420,6 +529,23 @@ func (g *Game) drawProcessList() {
 	}
 }
 
+// source returns the source line the process is at, if known.
+func (g *Game) source(p *vm.Process) string {
+	m := g.sourceMaps[p.Player.Number]
+	if m == nil {
+		return ""
+	}
+	offset, ok := g.cw.LoadOffset(p)
+	if !ok {
+		return ""
+	}
+	e, ok := m.Lookup(offset)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%d: %s", e.Line, e.Text)
+}
+
 func (g *Game) drawPlayerList() {
 	pv := g.playerListView.(*tview.List)
 
@@ -441,35 +567,37 @@ func (g *Game) drawState() {
 
 	fmt.Fprintf(sv, "Cycles: %d\n", g.cw.Cycle)
 	fmt.Fprintf(sv, "Current CyclesToDie: %d\n", g.cw.CurCyclesToDie)
-	fmt.Fprintf(sv, "Next CyclesToCheck: %d\n", g.cw.Config.CyclesToDie)
+	fmt.Fprintf(sv, "Next CyclesToCheck: %d\n", g.cw.CyclesToDie)
 	fmt.Fprintf(sv, "Memory Size: %d\n", g.cw.Config.MemSize)
 	fmt.Fprintf(sv, "IdxMod: %d\n", g.cw.Config.IdxMod)
 	fmt.Fprintf(sv, "NumLives: %d\n", g.cw.Config.NumLives)
 	fmt.Fprintf(sv, "CycleDelta: %d\n", g.cw.Config.CycleDelta)
+	fmt.Fprintf(sv, "MaxChecks: %d\n", g.cw.Config.MaxChecks)
 	fmt.Fprintf(sv, "Period live count: %d\n", g.cw.LiveCalls)
+	fmt.Fprintf(sv, "Checks: %d\n", g.cw.Checks)
 }
 
 func (g *Game) drawRAM() {
 	const width = 64
 	ramView := g.ramView.(*tview.Table)
 	ramView.SetSelectable(true, true)
-	for i, elem := range g.cw.Ram {
-		onClick := []func(){func() { g.cw.Messages <- vm.NewMessage(vm.MsgPause, nil, "") }}
+	for i, value := range g.cw.Ram {
+		onClick := []func(){g.pause}
 
-		cell := tview.NewTableCell(fmt.Sprintf("%02x", elem.Value))
-		if elem.Process != nil {
-			cell.SetTextColor(colors[elem.Process.ID%len(colors)])
-			if elem.AccessType == 1 {
+		cell := tview.NewTableCell(fmt.Sprintf("%02x", value))
+		if elem := g.cw.Owners[i]; elem.Player != 0 {
+			cell.SetTextColor(colors[elem.Player%len(colors)])
+			if elem.Access == vm.AccessWrite {
 				cell.SetAttributes(tcell.AttrBold)
-			} else if elem.AccessType == 2 {
+			} else if elem.Access == vm.AccessRead {
 				cell.SetAttributes(tcell.AttrItalic | tcell.AttrDim)
-			} else if elem.AccessType == 3 {
+			} else if elem.Access == vm.AccessReadIndex {
 				cell.SetAttributes(tcell.AttrItalic | tcell.AttrDim | tcell.AttrUnderline | tcell.AttrBlink)
 			}
 			onClick = append(onClick, func() {
-				g.cw.Messages <- vm.NewMessage(vm.MsgDebug, elem.Process, fmt.Sprintf("Yup!!! %d", elem.Process.ID))
+				g.log(nil, fmt.Sprintf("Yup!!! player %d", elem.Player))
 			})
-		} else if elem.Value == 0 {
+		} else if value == 0 {
 			cell.SetTextColor(tcell.ColorDimGray)
 			cell.SetAttributes(tcell.AttrDim)
 		}
@@ -477,7 +605,7 @@ func (g *Game) drawRAM() {
 			if !p.Player.Dead && i == int(p.PC) {
 				cell.SetAttributes(tcell.AttrReverse).SetTextColor(colors[p.ID%len(colors)])
 				onClick = append(onClick, func() {
-					g.cw.Messages <- vm.NewMessage(vm.MsgDebug, p, fmt.Sprintf("PC player %d", p.Player.Number))
+					g.log(p, fmt.Sprintf("PC player %d", p.Player.Number))
 				})
 			}
 		}
@@ -510,27 +638,57 @@ func main() {
 		colors = append(colors, v)
 	}
 
-	cfg, players, err := cli.ParseConfig()
+	cfg, players, opts, err := cli.ParseConfig()
 	if err != nil {
 		log.Fatalf("Failed to parse CLI config: %s.", err)
 	}
-
-	cw := vm.NewCorewar(cfg)
-	if err := cw.Round(); err != nil {
-		log.Fatalf("Failed to execute first round: %s.", err)
+	var replay *vm.Replay
+	if opts.Replay != "" {
+		replay, players, err = cli.LoadReplay(opts.Replay)
+		if err != nil {
+			log.Fatalf("Failed to load replay: %s.", err)
+		}
+		cfg = replay.Config
 	}
 
+	// The observer needs the game which needs the VM, set it once everything is created.
+	obs := &observer{}
+	cfg.Observer = obs
+	cfg.TrackOwnership = true
+	var cw *vm.Corewar
+	switch {
+	case replay != nil:
+		// No history, going back would get the replay out of sync.
+		replay.Config = cfg
+		cw, err = replay.NewCorewar()
+	case opts.Snapshot != "":
+		cfg.History = 100
+		cw, players, err = cli.LoadSnapshot(opts.Snapshot, cfg)
+	default:
+		cfg.History = 100
+		cw, err = vm.NewCorewar(cfg)
+	}
+	if err != nil {
+		log.Fatalf("Failed to create VM: %s.", err)
+	}
 	g := NewGame(context.Background(), cw)
+	g.replay = replay
+	g.sourceMaps = map[int]*asm.SourceMap{}
+	for _, p := range players {
+		if p.SourceMap != nil {
+			g.sourceMaps[p.Number] = p.SourceMap
+		}
+	}
+	obs.g = g
+	if err := g.round(); err != nil {
+		log.Fatalf("Failed to execute first round: %s.", err)
+	}
 
 	for _, p := range players {
 		pl2 := tview.NewTextView().SetText(dumpChampion(p.Data))
 
 		pl := tview.NewTextView().SetText(fmt.Sprintf("Player: %d (%s)\n\n", p.Number, p.Prog.GetDirective(op.NameCmdString)))
-		buf := &strings.Builder{}
-		for _, elem := range p.Prog.Nodes {
-			fmt.Fprintf(buf, "%s\n", elem.PrettyPrint(p.Prog.Nodes))
-		}
-		pl.SetText(buf.String())
+		pl.SetText(parser.Format(p.Prog.Nodes))
 
 		flex := tview.NewFlex().AddItem(pl, 0, 1, false).
 			AddItem(pl2, 0, 1, false)
@@ -1,17 +1,12 @@
 package disasm
 
 import (
-	"archive/tar"
-	"bytes"
-	"compress/gzip"
 	"crypto/md5"
 	"fmt"
-	"io"
-	"strings"
 
 	"go.creack.net/corewar/asm"
 	"go.creack.net/corewar/asm/parser"
-	"go.creack.net/corewar/assets"
+	"go.creack.net/corewar/library"
 	"go.creack.net/corewar/op"
 )
 
@@ -21,45 +16,31 @@ func md5sum(data []byte) string {
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
-// search is the md5 of the program (.cor after headers).
-func searchExistingSrc(targzData []byte, search string) ([]byte, error) {
-	r, err := gzip.NewReader(bytes.NewReader(targzData))
+// Decompile decodes the binary without looking for a known source,
+// with labels reconstructed at the jump targets.
+func Decompile(inputName string, binData []byte, warnOpts parser.WarningOptions) (*parser.Program, error) {
+	prog := &parser.Program{}
+	p, err := prog.Decode(binData, warnOpts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+		return nil, fmt.Errorf("failed to decode program: %w", err)
 	}
-	defer func() { _ = r.Close() }() // Best effort.
+	reconstructLabels(p)
 
-	// Unpack the tar archive.
-	tr := tar.NewReader(r)
-	for {
-		hdr, err := tr.Next()
-		if err != nil {
-			if err == io.EOF {
-				break // End of archive
-			}
-			return nil, fmt.Errorf("failed to read tar entry: %w", err)
-		}
-		// If we don't have a name, skip it.
-		if !strings.HasSuffix(hdr.Name, search+".s") {
-			continue
-		}
-		// Read the file content.
-		buf := bytes.NewBuffer(nil)
-		if _, err := io.Copy(buf, tr); err != nil {
-			return nil, fmt.Errorf("failed to read file %q: %w", hdr.Name, err)
-		}
-		return buf.Bytes(), nil
+	// Start over with a fresh program so it can be encoded with the labels.
+	pr := parser.NewProgram(p, warnOpts)
+	pr.AddWarnings(prog.Warnings())
+	if _, err := pr.Encode(); err != nil {
+		return nil, fmt.Errorf("failed to encode program: %w", err)
 	}
-
-	return nil, nil
+	return pr, nil
 }
 
-func Disam(inputName string, binData []byte, strict bool) (*parser.Program, error) {
-	prog := &parser.Program{}
-
-	p, err := prog.Decode(binData, strict)
+// Disam disassembles the binary. If it is known, the original source
+// is returned, otherwise, it is decompiled, see Decompile.
+func Disam(inputName string, binData []byte, warnOpts parser.WarningOptions) (*parser.Program, error) {
+	prog, err := Decompile(inputName, binData, warnOpts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode program: %w", err)
+		return nil, err
 	}
 
 	// Get the program part (i.e., code after headers) to get the md5.
@@ -68,24 +49,25 @@ func Disam(inputName string, binData []byte, strict bool) (*parser.Program, erro
 		return nil, fmt.Errorf("failed to encode program: %w", err)
 	}
 
-	exsitingSrc, err := searchExistingSrc(assets.CleanSrcsTargz, md5sum(buf))
+	lib, err := library.Default()
 	if err != nil {
-		return nil, fmt.Errorf("failed to unpack srcs: %w", err)
+		return nil, fmt.Errorf("failed to load the library: %w", err)
 	}
-	if exsitingSrc == nil {
+	existing := lib.ByHash(md5sum(buf))
+	if existing == nil {
 		// If we didn't find a match, return what we have.
 		return prog, nil
 	}
 	// log.Printf("Found match in known sources for %q.\n", inputName)
 
-	_, pr2, err := asm.Compile("known-srcs", string(exsitingSrc), strict)
+	_, pr2, err := asm.Compile("known-srcs", string(existing.Src), warnOpts)
 	if err != nil {
 		// Should not happen.
 		return nil, fmt.Errorf("failed to decode known srcs: %w", err)
 	}
 	p2 := pr2.Parser
-	actualName := p.GetDirective(op.NameCmdString)
-	actualComment := p.GetDirective(op.CommentCmdString)
+	actualName := prog.GetDirective(op.NameCmdString)
+	actualComment := prog.GetDirective(op.CommentCmdString)
 	for _, elem := range p2.Nodes {
 		if d, ok := elem.(*parser.Directive); ok {
 			if string(op.DirectiveChar)+d.Name == op.NameCmdString {
@@ -95,5 +77,11 @@ func Disam(inputName string, binData []byte, strict bool) (*parser.Program, erro
 			}
 		}
 	}
+	// The code is the same, only the warnings about the header of the binary are new.
+	for _, d := range prog.Warnings() {
+		if d.Code == parser.WarnHeaderMagic || d.Code == parser.WarnHeaderSize {
+			pr2.AddWarnings(parser.ErrorList{d})
+		}
+	}
 	return pr2, nil
 }
@@ -0,0 +1,157 @@
+package disasm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.creack.net/corewar/asm"
+	"go.creack.net/corewar/asm/parser"
+	"go.creack.net/corewar/op"
+)
+
+// compile assembles the given champion source.
+func compile(t *testing.T, src string) []byte {
+	t.Helper()
+	buf, _, err := asm.Compile("test.s", src, parser.WarningOptions{})
+	if err != nil {
+		t.Fatalf("Failed to compile: %s.", err)
+	}
+	return buf
+}
+
+// testDecompile checks the decompiled source and that it assembles back to the same bytes.
+func testDecompile(t *testing.T, src, want string) {
+	t.Helper()
+	bin := compile(t, src)
+	prog, err := Decompile("test.cor", bin, parser.WarningOptions{})
+	if err != nil {
+		t.Fatalf("Failed to decompile: %s.", err)
+	}
+	if got := parser.Format(prog.Nodes); got != want {
+		t.Fatalf("Unexpected source:\n%s\nexpected:\n%s", got, want)
+	}
+	if err := Verify("test.cor", bin, parser.WarningOptions{}); err != nil {
+		t.Fatalf("Failed to verify the round trip: %s.", err)
+	}
+}
+
+func TestDecompileLabels(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "backward and forward",
+			src:  ".name \"t\"\n.comment \"c\"\n\n\tsti\tr1, %:live, %1\nlive:\tlive\t%1\n\tld\t%0, r2\n\tzjmp\t%:live\n\tfork\t%:end\n\tst\tr1, :live\nend:\n",
+			want: ".name \"t\"\n.comment \"c\"\n\tsti     r1, %:l1, %1\n\nl1:\n\tlive    %1\n\tld      %0, r2\n\tzjmp    %:l1\n\tfork    %:l2\n\tst      r1, :l1\n\nl2:\n",
+		},
+		{
+			name: "numeric offset",
+			src:  ".name \"t\"\n.comment \"c\"\n\n\tzjmp\t%3\n\tlive\t%1\n",
+			want: ".name \"t\"\n.comment \"c\"\n\tzjmp    %:l1\n\nl1:\n\tlive    %1\n",
+		},
+		{
+			name: "not an instruction",
+			src:  ".name \"t\"\n.comment \"c\"\n\n\tzjmp\t%2\n\tlive\t%1\n",
+			want: ".name \"t\"\n.comment \"c\"\n\tzjmp    %2\n\tlive    %1\n",
+		},
+		{
+			name: "constant",
+			src:  ".name \"t\"\n.comment \"c\"\n\n\tsti\tr1, %0, %1\n\tld\t%5, r2\n",
+			want: ".name \"t\"\n.comment \"c\"\n\tsti     r1, %0, %1\n\tld      %5, r2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testDecompile(t, tt.src, tt.want)
+		})
+	}
+}
+
+func TestDecompileCode(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "invalid opcodes",
+			src:  ".name \"t\"\n.comment \"c\"\n.extend\n\n\tlive\t%1\n.code ff 00 17\n\tzjmp\t%-8\n",
+			want: ".name \"t\"\n.comment \"c\"\n.extend\n\nl1:\n\tlive    %1\n\t.code   ff\n\tnoop\n\t.code   17\n\tzjmp    %:l1\n",
+		},
+		{
+			name: "truncated instruction",
+			src:  ".name \"t\"\n.comment \"c\"\n.extend\n\n\tlive\t%1\n.code 01 00\n",
+			want: ".name \"t\"\n.comment \"c\"\n.extend\n\tlive    %1\n\t.code   01 00\n",
+		},
+		{
+			name: "invalid parameter types",
+			src:  ".name \"t\"\n.comment \"c\"\n.extend\n\n.code 0b ff\n\tlive\t%1\n",
+			want: ".name \"t\"\n.comment \"c\"\n.extend\n\t.code   0b ff\n\tlive    %1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testDecompile(t, tt.src, tt.want)
+		})
+	}
+}
+
+func TestVerifyMismatch(t *testing.T) {
+	bin := compile(t, ".name \"t\"\n.comment \"c\"\n\n\tlive\t%1\n")
+	// The header size doesn't count the trailing byte, the reassembled one does.
+	bin = append(bin, 0x01)
+	var mismatch *MismatchError
+	if err := Verify("test.cor", bin, parser.WarningOptions{Disabled: map[string]bool{parser.WarnHeaderSize: true}}); !errors.As(err, &mismatch) || !errors.Is(err, ErrMismatch) {
+		t.Fatalf("Unexpected error: %v, expected a *MismatchError.", err)
+	}
+	// The last byte of the code size, 5 bytes in the original, 6 once reassembled.
+	headerSize, _, _ := op.HeaderStructSize()
+	if mismatch.Offset >= headerSize || mismatch.Node != nil || mismatch.Want[mismatch.Offset] != 0x05 || mismatch.Got[mismatch.Offset] != 0x06 {
+		t.Fatalf("Unexpected mismatch: %s.", mismatch)
+	}
+	if want := fmt.Sprintf("%s at offset %d (header): want 05, got 06", ErrMismatch, mismatch.Offset); mismatch.Error() != want {
+		t.Fatalf("Unexpected error: %q, expected %q.", mismatch.Error(), want)
+	}
+}
+
+func TestMismatchInCode(t *testing.T) {
+	const src = ".name \"t\"\n.comment \"c\"\n\n\tld\t%0, r2\n\tlive\t%1\n\tzjmp\t%-5\n"
+	want := compile(t, src)
+	prog, err := Decompile("test.cor", want, parser.WarningOptions{})
+	if err != nil {
+		t.Fatalf("Failed to decompile: %s.", err)
+	}
+	// Same program, but the live for player 2.
+	got := compile(t, strings.Replace(src, "%1", "%2", 1))
+
+	mismatch := newMismatch(prog, want, got)
+	headerSize, _, _ := op.HeaderStructSize()
+	if offset := headerSize + 7 + 4; mismatch.Offset != offset {
+		t.Fatalf("Mismatch at offset %d, expected %d.", mismatch.Offset, offset)
+	}
+	if ins, ok := mismatch.Node.(*parser.Instruction); !ok || ins.OpCode.Name != "live" {
+		t.Fatalf("Mismatch in %v, expected the live instruction.", mismatch.Node)
+	}
+	if want := fmt.Sprintf("%s at offset %d (<live (%%1)>): want 01, got 02", ErrMismatch, mismatch.Offset); mismatch.Error() != want {
+		t.Fatalf("Unexpected error: %q, expected %q.", mismatch.Error(), want)
+	}
+}
+
+func TestVerifyCorpus(t *testing.T) {
+	results, err := VerifyCorpus(parser.WarningOptions{})
+	if err != nil {
+		t.Fatalf("Failed to verify the corpus: %s.", err)
+	}
+	if len(results) == 0 {
+		t.Fatal("Empty corpus.")
+	}
+	for _, res := range results {
+		if res.Err != nil {
+			t.Errorf("%s: %s.", res.Name, res.Err)
+		}
+	}
+}
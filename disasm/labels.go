@@ -0,0 +1,109 @@
+package disasm
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.creack.net/corewar/asm/parser"
+	"go.creack.net/corewar/op"
+)
+
+// isLabelTarget reports whether the i-th parameter of the instruction is
+// an offset from it, i.e. written as a label reference in sources.
+func isLabelTarget(ins *parser.Instruction, i int) bool {
+	param := ins.Params[i]
+	switch ins.OpCode.Name {
+	case "zjmp", "fork", "lfork":
+		return true
+	case "ldi", "lldi", "sti":
+		// Zero is most likely a constant rather than the instruction itself.
+		return param.Typ == op.TDir && param.Value != 0
+	case "ld", "lld":
+		return i == 0 && param.Typ == op.TInd
+	case "st":
+		return i == 1 && param.Typ == op.TInd
+	}
+	return false
+}
+
+// nodeSize returns the size in bytes of the decoded node.
+func nodeSize(n parser.Node) int {
+	switch n := n.(type) {
+	case *parser.Instruction:
+		return n.Size
+	case *parser.Directive:
+		if string(op.DirectiveChar)+n.Name == op.CodeCmdString {
+			return len(strings.Fields(n.Value))
+		}
+	}
+	return 0
+}
+
+// reconstructLabels adds labels at the targets of the jumps, forks and
+// relative loads and stores landing on an instruction, and rewrites the
+// parameters as references to them. The program assembles the same.
+func reconstructLabels(p *parser.Parser) {
+	// Offset of each node, and the node index at each offset.
+	offsets := make([]int, len(p.Nodes))
+	starts := map[int]int{}
+	size := 0
+	for i, n := range p.Nodes {
+		offsets[i] = size
+		if _, ok := n.(*parser.Instruction); ok {
+			starts[size] = i
+		}
+		size += nodeSize(n)
+	}
+	// The end of the program is a valid target as well.
+	starts[size] = len(p.Nodes)
+
+	// Find the targets.
+	type ref struct {
+		param  *parser.Parameter
+		target int
+	}
+	var refs []ref
+	targets := map[int]string{}
+	for i, n := range p.Nodes {
+		ins, ok := n.(*parser.Instruction)
+		if !ok {
+			continue
+		}
+		for j, param := range ins.Params {
+			if !isLabelTarget(ins, j) {
+				continue
+			}
+			target := offsets[i] + int(int16(param.Value))
+			if _, ok := starts[target]; !ok {
+				continue
+			}
+			refs = append(refs, ref{param: param, target: target})
+			targets[target] = ""
+		}
+	}
+	if len(refs) == 0 {
+		return
+	}
+
+	// Name the labels in address order.
+	addrs := make([]int, 0, len(targets))
+	for addr := range targets {
+		addrs = append(addrs, addr)
+	}
+	slices.Sort(addrs)
+	for i, addr := range addrs {
+		targets[addr] = "l" + strconv.Itoa(i+1)
+	}
+
+	for _, elem := range refs {
+		elem.param.RawValue = fmt.Sprintf("%c%s", op.LabelChar, targets[elem.target])
+	}
+
+	// Insert the labels, from the end so the indexes stay valid.
+	for _, addr := range slices.Backward(addrs) {
+		idx := starts[addr]
+		p.Nodes = slices.Insert(p.Nodes, idx, parser.Node(&parser.Label{Name: targets[addr]}))
+	}
+}
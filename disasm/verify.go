@@ -0,0 +1,109 @@
+package disasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"go.creack.net/corewar/asm"
+	"go.creack.net/corewar/asm/parser"
+	"go.creack.net/corewar/library"
+	"go.creack.net/corewar/op"
+)
+
+// ErrMismatch is returned when the disassembly doesn't assemble back to the same bytes.
+var ErrMismatch = errors.New("reassembled program differs")
+
+// MismatchError describes where the reassembled program differs first.
+type MismatchError struct {
+	Offset int         // Offset in the binary, header included.
+	Node   parser.Node // Decoded node covering the offset, nil when in the header.
+	Want   []byte      // Original binary.
+	Got    []byte      // Reassembled binary.
+}
+
+func (e *MismatchError) Error() string {
+	where := "header"
+	if e.Node != nil {
+		where = fmt.Sprint(e.Node)
+	}
+	return fmt.Sprintf("%s at offset %d (%s): %s", ErrMismatch, e.Offset, where, byteAt(e.Want, e.Offset, e.Got))
+}
+
+func (e *MismatchError) Unwrap() error { return ErrMismatch }
+
+// byteAt formats the bytes of both binaries at the given offset.
+func byteAt(want []byte, offset int, got []byte) string {
+	str := func(b []byte) string {
+		if offset >= len(b) {
+			return "EOF"
+		}
+		return fmt.Sprintf("%02x", b[offset])
+	}
+	return fmt.Sprintf("want %s, got %s", str(want), str(got))
+}
+
+// Verify decompiles the binary, assembles the result and compares
+// it with the original, header included. Returns a *MismatchError if
+// they differ.
+func Verify(inputName string, binData []byte, warnOpts parser.WarningOptions) error {
+	prog, err := Decompile(inputName, binData, warnOpts)
+	if err != nil {
+		return err
+	}
+	got, _, err := asm.Compile(inputName, parser.Format(prog.Nodes), warnOpts)
+	if err != nil {
+		return fmt.Errorf("failed to reassemble: %w", err)
+	}
+	if bytes.Equal(got, binData) {
+		return nil
+	}
+
+	return newMismatch(prog, binData, got)
+}
+
+// newMismatch returns the first difference between the original binary
+// and the one reassembled from its decompiled program.
+func newMismatch(prog *parser.Program, want, got []byte) *MismatchError {
+	offset := 0
+	for offset < len(got) && offset < len(want) && got[offset] == want[offset] {
+		offset++
+	}
+	e := &MismatchError{Offset: offset, Want: want, Got: got}
+
+	// Look for the node covering the offset in the code.
+	headerSize, _, _ := op.HeaderStructSize()
+	for i, n := range prog.Nodes {
+		start := headerSize + prog.Offsets()[i]
+		if size := nodeSize(n); offset >= start && offset < start+size {
+			e.Node = n
+			break
+		}
+	}
+	return e
+}
+
+// CorpusResult is the result of Verify for a source of the corpus.
+type CorpusResult struct {
+	Name string
+	Err  error // Nil when it round trips.
+}
+
+// VerifyCorpus verifies the compiled champions of the embedded corpus, see Verify.
+func VerifyCorpus(warnOpts parser.WarningOptions) ([]CorpusResult, error) {
+	lib, err := library.Default()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the library: %w", err)
+	}
+	out := make([]CorpusResult, 0, len(lib.Entries))
+	for _, e := range lib.Entries {
+		err := e.Compile()
+		if err != nil {
+			err = fmt.Errorf("failed to compile: %w", err)
+		} else {
+			err = Verify(e.Path, e.Bin, warnOpts)
+		}
+		out = append(out, CorpusResult{Name: e.Path, Err: err})
+	}
+	return out, nil
+}
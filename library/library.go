@@ -0,0 +1,335 @@
+// Package library indexes a collection of champion sources,
+// like the embedded corpus, by program hash and by name.
+package library
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.creack.net/corewar/asm"
+	"go.creack.net/corewar/asm/parser"
+	"go.creack.net/corewar/assets"
+	"go.creack.net/corewar/op"
+)
+
+// Errors returned by Find.
+var (
+	ErrNotFound  = errors.New("champion not found")
+	ErrAmbiguous = errors.New("ambiguous champion name")
+)
+
+// hashSep separates the champion name from the hash of its program
+// in the corpus file names, e.g. "zork===94617a86622c68541a5c5e675c775bc0.s".
+const hashSep = "==="
+
+// Entry is a champion of the library.
+type Entry struct {
+	Path string // Path in the archive or the directory.
+	Src  []byte // Source.
+
+	// Set by Compile, when the source compiles.
+	Name    string // From .name.
+	Comment string // From .comment.
+	Hash    string // md5 of the code, header excluded.
+	Bin     []byte // Compiled champion, header included.
+	Err     error  // Compilation error, if any.
+
+	once sync.Once
+}
+
+// Compile compiles the source on first call and sets the fields
+// above. Returns the compilation error, if any.
+func (e *Entry) Compile() error {
+	e.once.Do(func() {
+		bin, pr, err := asm.Compile(e.Path, string(e.Src), parser.WarningOptions{})
+		if err != nil {
+			e.Err = err
+			return
+		}
+		headerSize, _, _ := op.HeaderStructSize()
+		e.Bin = bin
+		e.Name = pr.GetDirective(op.NameCmdString)
+		e.Comment = pr.GetDirective(op.CommentCmdString)
+		e.Hash = fmt.Sprintf("%x", md5.Sum(bin[headerSize:]))
+	})
+	return e.Err
+}
+
+// Base returns the file name without the .s extension.
+func (e *Entry) Base() string {
+	return strings.TrimSuffix(filepath.Base(e.Path), ".s")
+}
+
+// ShortName returns the file name without the hash, if any, nor the .s extension.
+func (e *Entry) ShortName() string {
+	name, _, _ := strings.Cut(e.Base(), hashSep)
+	return name
+}
+
+// FileHash returns the hash from the file name, empty if none.
+func (e *Entry) FileHash() string {
+	_, hash, _ := strings.Cut(e.Base(), hashSep)
+	return hash
+}
+
+// Size returns the size of the code, header excluded, 0 if it doesn't compile.
+func (e *Entry) Size() int {
+	_ = e.Compile() // Size 0 on error.
+	headerSize, _, _ := op.HeaderStructSize()
+	return max(0, len(e.Bin)-headerSize)
+}
+
+// Library is an index of champions.
+//
+// Only the file names are indexed upfront, the sources are compiled
+// when needed, see Entry.Compile.
+type Library struct {
+	Entries []*Entry // In the order of the archive or directory.
+
+	byFileHash map[string][]*Entry // Hash from the file name -> entries.
+	byName     map[string][]*Entry // Lowercase base and short name -> entries.
+
+	hashOnce sync.Once
+	byHash   map[string]*Entry // md5 of the code -> first entry with it.
+}
+
+// Default returns the library of the embedded corpus, indexed on first use.
+var Default = sync.OnceValues(func() (*Library, error) {
+	return Load(assets.CleanSrcsTargz)
+})
+
+// New indexes the given entries by file name, only Path and Src need to be set.
+func New(entries []*Entry) *Library {
+	l := &Library{
+		Entries:    entries,
+		byFileHash: map[string][]*Entry{},
+		byName:     map[string][]*Entry{},
+	}
+	for _, e := range entries {
+		if h := e.FileHash(); h != "" {
+			l.byFileHash[h] = append(l.byFileHash[h], e)
+		}
+		for _, name := range []string{e.Base(), e.ShortName()} {
+			name = strings.ToLower(name)
+			if !slices.Contains(l.byName[name], e) {
+				l.byName[name] = append(l.byName[name], e)
+			}
+		}
+	}
+	return l
+}
+
+// Load indexes the .s files of the given tar.gz archive.
+func Load(targzData []byte) (*Library, error) {
+	r, err := gzip.NewReader(bytes.NewReader(targzData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer func() { _ = r.Close() }() // Best effort.
+
+	var entries []*Entry
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if err == io.EOF {
+				break // End of archive
+			}
+			return nil, fmt.Errorf("failed to read tar entry: %w", err)
+		}
+		if !strings.HasSuffix(hdr.Name, ".s") {
+			continue
+		}
+		buf, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %q: %w", hdr.Name, err)
+		}
+		entries = append(entries, &Entry{Path: hdr.Name, Src: buf})
+	}
+	return New(entries), nil
+}
+
+// LoadDir indexes the .s files of the given directory, recursively.
+func LoadDir(dir string) (*Library, error) {
+	var entries []*Entry
+	if err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".s") {
+			return nil
+		}
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read file %q: %w", path, err)
+		}
+		entries = append(entries, &Entry{Path: path, Src: buf})
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to walk %q: %w", dir, err)
+	}
+	return New(entries), nil
+}
+
+// ByHash returns the entry whose code has the given md5, nil if none.
+//
+// The file names carrying a hash are tried first, the entry gets compiled
+// to check it, see cmd/corpus dedupe for the mismatches. On a miss, all
+// the entries are compiled once and indexed by the hash of their code,
+// so a file with a wrong hash in its name is still found.
+func (l *Library) ByHash(hash string) *Entry {
+	for _, e := range l.byFileHash[hash] {
+		if e.Compile() == nil && e.Hash == hash {
+			return e
+		}
+	}
+	l.hashOnce.Do(func() {
+		l.byHash = map[string]*Entry{}
+		for _, e := range l.Entries {
+			if e.Compile() != nil {
+				continue
+			}
+			if _, ok := l.byHash[e.Hash]; !ok {
+				l.byHash[e.Hash] = e
+			}
+		}
+	})
+	return l.byHash[hash]
+}
+
+// ByName returns the entries with the given file name, with or without
+// the hash and the .s extension. Case insensitive.
+func (l *Library) ByName(name string) []*Entry {
+	return l.byName[strings.ToLower(strings.TrimSuffix(name, ".s"))]
+}
+
+// match is an entry with its score from search, lower is better.
+type match struct {
+	entry *Entry
+	score int
+}
+
+// search returns the entries close to the given name, best matches first.
+func (l *Library) search(name string) []match {
+	name = strings.ToLower(strings.TrimSuffix(name, ".s"))
+	score := func(e *Entry) int {
+		_ = e.Compile() // Only the file name on error.
+		best := -1
+		for _, candidate := range []string{e.Base(), e.ShortName(), e.Name} {
+			if candidate == "" {
+				continue
+			}
+			candidate = strings.ToLower(candidate)
+			s := -1
+			switch {
+			case candidate == name:
+				s = 0
+			case strings.HasPrefix(candidate, name):
+				s = 1
+			case strings.Contains(candidate, name):
+				s = 2
+			case levenshtein(candidate, name) <= len(name)/4:
+				s = 3
+			}
+			if s != -1 && (best == -1 || s < best) {
+				best = s
+			}
+		}
+		return best
+	}
+
+	var matches []match
+	for _, e := range l.Entries {
+		if s := score(e); s != -1 {
+			matches = append(matches, match{entry: e, score: s})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b match) int {
+		if a.score != b.score {
+			return a.score - b.score
+		}
+		return strings.Compare(a.entry.Base(), b.entry.Base())
+	})
+	return matches
+}
+
+// Search returns the entries whose file or champion name is close to the
+// given one, best matches first: same name ignoring the case, then
+// prefix, substring and finally a typo every 4 characters.
+func (l *Library) Search(name string) []*Entry {
+	matches := l.search(name)
+	out := make([]*Entry, 0, len(matches))
+	for _, m := range matches {
+		out = append(out, m.entry)
+	}
+	return out
+}
+
+// Lookup returns the entries with the given name, see ByName,
+// or the closest ones if none, see Search.
+func (l *Library) Lookup(name string) []*Entry {
+	if out := l.ByName(name); len(out) > 0 {
+		return out
+	}
+	return l.Search(name)
+}
+
+// Find returns the first entry with the given name, see ByName,
+// or the only best match, see Search. Several champions can share
+// a name, the file name with the hash, or its start, picks another.
+func (l *Library) Find(name string) (*Entry, error) {
+	if matches := l.ByName(name); len(matches) > 0 {
+		return matches[0], nil
+	}
+	var matches []*Entry
+	found := l.search(name)
+	for _, m := range found {
+		if m.score != found[0].score {
+			break
+		}
+		matches = append(matches, m.entry)
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+	case 1:
+		return matches[0], nil
+	}
+	names := make([]string, 0, len(matches))
+	for _, e := range matches {
+		names = append(names, e.Base())
+	}
+	return nil, fmt.Errorf("%w: %q matches %s", ErrAmbiguous, name, strings.Join(names, ", "))
+}
+
+// levenshtein returns the edit distance between the two strings.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
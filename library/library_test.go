@@ -0,0 +1,237 @@
+package library
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// champion returns the source of a champion, the live value makes its program unique.
+func champion(name string, live int) []byte {
+	return fmt.Appendf(nil, ".name %q\n.comment \"test\"\n\nl:\tlive\t%%%d\n\tzjmp\t%%:l\n", name, live)
+}
+
+// hashOf returns the hash of the program of the given source.
+func hashOf(t *testing.T, src []byte) string {
+	t.Helper()
+	e := &Entry{Path: "tmp.s", Src: src}
+	if err := e.Compile(); err != nil {
+		t.Fatalf("Failed to compile: %s.", err)
+	}
+	return e.Hash
+}
+
+// testLibrary returns a library named like the corpus, with the hash of the
+// program in the file names, and the entries by short name.
+func testLibrary(t *testing.T) (*Library, map[string]*Entry) {
+	t.Helper()
+	var entries []*Entry
+	byName := map[string]*Entry{}
+	add := func(key, path string, src []byte) {
+		e := &Entry{Path: path, Src: src}
+		entries = append(entries, e)
+		byName[key] = e
+	}
+	for i, name := range []string{"zork", "Batman", "batmobile", "robin"} {
+		src := champion(name, i+1)
+		add(name, fmt.Sprintf("srcs/%s===%s.s", name, hashOf(t, src)), src)
+	}
+	// Same name, other program.
+	src := champion("zork", 10)
+	add("zork2", fmt.Sprintf("srcs/zork===%s.s", hashOf(t, src)), src)
+	// Wrong hash in the file name, taken from robin, and before it.
+	add("liar", fmt.Sprintf("srcs/liar===%s.s", byName["robin"].FileHash()), champion("liar", 11))
+	entries = slices.Insert(entries[:len(entries)-1], 0, entries[len(entries)-1])
+	// No hash in the file name.
+	add("plain", "srcs/plain.s", champion("plain", 12))
+	add("broken", "srcs/broken.s", []byte("not a champion\n"))
+	return New(entries), byName
+}
+
+func TestEntry(t *testing.T) {
+	_, byName := testLibrary(t)
+	e := byName["Batman"]
+	if err := e.Compile(); err != nil || e.Name != "Batman" || e.Comment != "test" || e.Size() != 8 {
+		t.Fatalf("Unexpected entry: %s %q %q of size %d.", e.Err, e.Name, e.Comment, e.Size())
+	}
+	if e.Base() != "Batman==="+e.Hash || e.ShortName() != "Batman" || e.FileHash() != e.Hash {
+		t.Fatalf("Unexpected names of %q: %q, %q, %q.", e.Path, e.Base(), e.ShortName(), e.FileHash())
+	}
+	if e := byName["plain"]; e.ShortName() != "plain" || e.FileHash() != "" {
+		t.Fatalf("Unexpected names of %q: %q, %q.", e.Path, e.ShortName(), e.FileHash())
+	}
+	if e := byName["broken"]; e.Compile() == nil || e.Bin != nil || e.Size() != 0 {
+		t.Fatalf("Broken entry compiled to %d bytes.", e.Size())
+	}
+}
+
+func TestByName(t *testing.T) {
+	lib, byName := testLibrary(t)
+	tests := []struct {
+		name string
+		want []string // Keys of the entries.
+	}{
+		{name: "robin", want: []string{"robin"}},
+		{name: "ROBIN.s", want: []string{"robin"}},
+		{name: "zork", want: []string{"zork", "zork2"}},
+		{name: byName["zork2"].Base(), want: []string{"zork2"}},
+		{name: "plain.s", want: []string{"plain"}},
+		{name: "batm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lib.ByName(tt.name)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Found %d entries, expected %d.", len(got), len(tt.want))
+			}
+			for i, e := range got {
+				if e != byName[tt.want[i]] {
+					t.Fatalf("Found %q, expected %q.", e.Path, byName[tt.want[i]].Path)
+				}
+			}
+		})
+	}
+}
+
+func TestByHash(t *testing.T) {
+	lib, byName := testLibrary(t)
+	tests := []struct {
+		name string
+		hash string
+		want string // Key of the entry, empty for none.
+	}{
+		{name: "file name", hash: hashOf(t, byName["batmobile"].Src), want: "batmobile"},
+		{name: "shared name", hash: hashOf(t, byName["zork2"].Src), want: "zork2"},
+		{name: "wrong file name before", hash: hashOf(t, byName["robin"].Src), want: "robin"},
+		{name: "wrong file name", hash: hashOf(t, byName["liar"].Src), want: "liar"},
+		{name: "no file hash", hash: hashOf(t, byName["plain"].Src), want: "plain"},
+		{name: "unknown", hash: "0123456789abcdef0123456789abcdef"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := lib.ByHash(tt.hash)
+			if tt.want == "" {
+				if e != nil {
+					t.Fatalf("Unexpected entry %q.", e.Path)
+				}
+				return
+			}
+			if e != byName[tt.want] {
+				t.Fatalf("Unexpected entry %v, expected %q.", e, byName[tt.want].Path)
+			}
+		})
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatalf("Failed to create the directory: %s.", err)
+	}
+	for path, src := range map[string][]byte{
+		"zork.s":       champion("zork", 1),
+		"sub/robin.s":  champion("robin", 2),
+		"notes.txt":    []byte("not a champion\n"),
+		"sub/broken.s": []byte("not a champion\n"),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, path), src, 0o644); err != nil {
+			t.Fatalf("Failed to write %q: %s.", path, err)
+		}
+	}
+	lib, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to load: %s.", err)
+	}
+	if len(lib.Entries) != 3 {
+		t.Fatalf("Loaded %d entries, expected 3.", len(lib.Entries))
+	}
+	if e := lib.ByName("robin"); len(e) != 1 || e[0].Compile() != nil || e[0].Name != "robin" {
+		t.Fatalf("Unexpected robin entries: %v.", e)
+	}
+}
+
+func TestFind(t *testing.T) {
+	lib, byName := testLibrary(t)
+	tests := []struct {
+		name string
+		want string // Key of the entry, empty for an error.
+		err  error
+	}{
+		{name: "robin", want: "robin"},
+		{name: "ROBIN.s", want: "robin"},
+		{name: "zork", want: "zork"}, // The first one.
+		{name: byName["zork2"].Base(), want: "zork2"},
+		{name: "zork===" + byName["zork2"].FileHash()[:6], want: "zork2"},
+		{name: "batm", err: ErrAmbiguous},
+		{name: "batmo", want: "batmobile"},
+		{name: "robn", want: "robin"},
+		{name: "nope", err: ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := lib.Find(tt.name)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Unexpected error: %v, expected %s.", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to find %q: %s.", tt.name, err)
+			}
+			if e != byName[tt.want] {
+				t.Fatalf("Found %q, expected %q.", e.Path, byName[tt.want].Path)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	lib, byName := testLibrary(t)
+	tests := []struct {
+		name string
+		want []string // Keys of the entries, best first.
+	}{
+		{name: "batman", want: []string{"Batman"}},
+		{name: "bat", want: []string{"Batman", "batmobile"}},
+		{name: "mobile", want: []string{"batmobile"}},
+		{name: "robn", want: []string{"robin"}},
+		{name: "nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lib.Search(tt.name)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Found %d entries, expected %d.", len(got), len(tt.want))
+			}
+			for i, e := range got {
+				if e != byName[tt.want[i]] {
+					t.Fatalf("Found %q, expected %q.", e.Path, byName[tt.want[i]].Path)
+				}
+			}
+		})
+	}
+	// Exact names first, the closest ones otherwise.
+	if got := lib.Lookup("zork"); len(got) != 2 {
+		t.Fatalf("Looked up %d zork, expected 2.", len(got))
+	}
+	if got := lib.Lookup("robn"); len(got) != 1 || got[0] != byName["robin"] {
+		t.Fatalf("Unexpected lookup of robn: %v.", got)
+	}
+}
+
+func TestLazyCompile(t *testing.T) {
+	lib, byName := testLibrary(t)
+	if _, err := lib.Find("robin"); err != nil {
+		t.Fatalf("Failed to find robin: %s.", err)
+	}
+	if e := byName["robin"]; e.Bin != nil {
+		t.Fatalf("Finding %q by name compiled it.", e.Path)
+	}
+	if err := byName["broken"].Compile(); err == nil {
+		t.Fatal("Compiling a broken source should fail.")
+	}
+}
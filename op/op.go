@@ -3,6 +3,7 @@ package op
 const (
 	MemSize       = 4 * 1024    // Memory size in bytes.
 	IdxMod        = MemSize / 8 // Index modulo, i.e. how far can a player go in the memory (except for long instructions).
+	ChampMaxSize  = MemSize / 6 // Maximum size of the code of a champion, header excluded.
 	MaxArgsNumber = 4           // This may not be changed. Arbitrary rule. // TODO: Add validation for this.
 )
 
@@ -81,4 +82,5 @@ const (
 	CyclesToDie = 1536 // Number of cycles to be declared dead.
 	CycleDelta  = 50   // Number of cycles to be remove from CyclesToDie after NumLives.
 	NumLives    = 21   // Number of 'live' calls before updating CyclesToDie.
+	MaxChecks   = 10   // Number of checks without reaching NumLives before updating CyclesToDie anyway.
 )
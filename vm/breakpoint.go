@@ -0,0 +1,180 @@
+package vm
+
+import (
+	"fmt"
+
+	"go.creack.net/corewar/op"
+)
+
+// BreakKind is the condition of a breakpoint.
+type BreakKind int
+
+// Available breakpoint kinds.
+const (
+	BreakAddr   BreakKind = iota // A process moves to Addr.
+	BreakOpcode                  // A process of Player executes OpCode.
+	BreakCycle                   // The game reaches Cycle.
+	BreakWatch                   // A process writes to [Addr, Addr+Size).
+)
+
+func (k BreakKind) String() string {
+	switch k {
+	case BreakAddr:
+		return "breakpoint"
+	case BreakOpcode:
+		return "opcode breakpoint"
+	case BreakCycle:
+		return "cycle breakpoint"
+	case BreakWatch:
+		return "watchpoint"
+	default:
+		return "unknown"
+	}
+}
+
+// Breakpoint pauses the game when its condition is met.
+type Breakpoint struct {
+	ID     int // Set by AddBreakpoint.
+	Kind   BreakKind
+	Addr   uint32 // BreakAddr and BreakWatch.
+	Size   int    // BreakWatch, in bytes.
+	OpCode byte   // BreakOpcode.
+	Player int    // BreakOpcode, player number, 0 for any player.
+	Cycle  int    // BreakCycle.
+}
+
+func (b Breakpoint) String() string {
+	switch b.Kind {
+	case BreakAddr:
+		return fmt.Sprintf("%s %d at 0x%04x", b.Kind, b.ID, b.Addr)
+	case BreakOpcode:
+		name := fmt.Sprintf("0x%02x", b.OpCode)
+		if int(b.OpCode) < len(op.OpCodeTable) {
+			name = op.OpCodeTable[b.OpCode].Name
+		}
+		if b.Player == 0 {
+			return fmt.Sprintf("%s %d on %s", b.Kind, b.ID, name)
+		}
+		return fmt.Sprintf("%s %d on %s by player %d", b.Kind, b.ID, name, b.Player)
+	case BreakCycle:
+		return fmt.Sprintf("%s %d at cycle %d", b.Kind, b.ID, b.Cycle)
+	case BreakWatch:
+		return fmt.Sprintf("%s %d on %d bytes at 0x%04x", b.Kind, b.ID, b.Size, b.Addr)
+	default:
+		return fmt.Sprintf("%s %d", b.Kind, b.ID)
+	}
+}
+
+// BreakHit is a breakpoint being triggered.
+type BreakHit struct {
+	Breakpoint Breakpoint
+	Process    *Process // Nil for BreakCycle.
+	Cycle      int
+	Addr       uint32 // PC for BreakAddr and BreakOpcode, written address for BreakWatch.
+}
+
+// BreakError is returned by Round when breakpoints got triggered.
+// The round is complete and the game is on the cycle right after
+// the hits, it can go on with the next round.
+type BreakError struct {
+	Hits []BreakHit // In order.
+}
+
+func (h BreakHit) String() string {
+	msg := fmt.Sprintf("cycle %d: %s", h.Cycle, h.Breakpoint)
+	if h.Process != nil {
+		msg = fmt.Sprintf("%s hit by process %d (player %d) at 0x%04x", msg, h.Process.ID, h.Process.Player.Number, h.Addr)
+	}
+	return msg
+}
+
+func (e *BreakError) Error() string {
+	msg := e.Hits[0].String()
+	if len(e.Hits) > 1 {
+		msg = fmt.Sprintf("%s, and %d more", msg, len(e.Hits)-1)
+	}
+	return msg
+}
+
+// AddBreakpoint adds the breakpoint and returns its ID.
+func (cw *Corewar) AddBreakpoint(b Breakpoint) int {
+	cw.nextBreakID++
+	b.ID = cw.nextBreakID
+	if len(cw.Ram) > 0 {
+		b.Addr %= uint32(len(cw.Ram))
+	}
+	cw.breakpoints = append(cw.breakpoints, b)
+	return b.ID
+}
+
+// RemoveBreakpoint removes the breakpoint with the given ID.
+// Returns false if there is none.
+func (cw *Corewar) RemoveBreakpoint(id int) bool {
+	for i, elem := range cw.breakpoints {
+		if elem.ID == id {
+			cw.breakpoints = append(cw.breakpoints[:i], cw.breakpoints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Breakpoints returns the current breakpoints.
+func (cw *Corewar) Breakpoints() []Breakpoint {
+	return append([]Breakpoint(nil), cw.breakpoints...)
+}
+
+// nextBreakCycle returns the next cycle with a breakpoint, -1 if none.
+func (cw *Corewar) nextBreakCycle() int {
+	next := -1
+	for _, elem := range cw.breakpoints {
+		if elem.Kind == BreakCycle && elem.Cycle > cw.Cycle && (next < 0 || elem.Cycle < next) {
+			next = elem.Cycle
+		}
+	}
+	return next
+}
+
+// checkAddr is called when a process moved or got forked.
+func (cw *Corewar) checkAddr(p *Process) {
+	for _, elem := range cw.breakpoints {
+		if elem.Kind == BreakAddr && elem.Addr == p.PC {
+			cw.hits = append(cw.hits, BreakHit{Breakpoint: elem, Process: p, Cycle: cw.Cycle, Addr: p.PC})
+		}
+	}
+}
+
+// checkOpcode is called when a process executed the instruction at pc.
+func (cw *Corewar) checkOpcode(p *Process, code byte, pc uint32) {
+	for _, elem := range cw.breakpoints {
+		if elem.Kind == BreakOpcode && elem.OpCode == code && (elem.Player == 0 || elem.Player == p.Player.Number) {
+			cw.hits = append(cw.hits, BreakHit{Breakpoint: elem, Process: p, Cycle: cw.Cycle, Addr: pc})
+		}
+	}
+}
+
+// checkWatch is called when a process writes to the memory.
+func (cw *Corewar) checkWatch(p *Process, addr uint32, size int) {
+	memSize := uint32(len(cw.Ram))
+	for _, elem := range cw.breakpoints {
+		if elem.Kind != BreakWatch {
+			continue
+		}
+		for i := range uint32(size) {
+			a := (addr + i) % memSize
+			if (a+memSize-elem.Addr)%memSize < uint32(elem.Size) {
+				cw.hits = append(cw.hits, BreakHit{Breakpoint: elem, Process: p, Cycle: cw.Cycle, Addr: a})
+				break
+			}
+		}
+	}
+}
+
+// checkCycle is called when the game reached a new cycle.
+func (cw *Corewar) checkCycle() {
+	for _, elem := range cw.breakpoints {
+		if elem.Kind == BreakCycle && elem.Cycle == cw.Cycle {
+			cw.hits = append(cw.hits, BreakHit{Breakpoint: elem, Cycle: cw.Cycle})
+		}
+	}
+}
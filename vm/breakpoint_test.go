@@ -0,0 +1,122 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBreakpoints(t *testing.T) {
+	tests := []struct {
+		name   string
+		bp     Breakpoint
+		hit    bool
+		player int    // Player of the process hitting it, 0 for none.
+		addr   uint32 // Address of the hit.
+		cycle  int    // Cycle of the hit, 0 to skip the check.
+	}{
+		{name: "address", bp: Breakpoint{Kind: BreakAddr, Addr: 21}, hit: true, player: 1, addr: 21},
+		{name: "address wraps", bp: Breakpoint{Kind: BreakAddr, Addr: 4096 + 21}, hit: true, player: 1, addr: 21},
+		{name: "opcode", bp: Breakpoint{Kind: BreakOpcode, OpCode: 0x0c}, hit: true, player: 1, addr: 26},
+		{name: "opcode of player", bp: Breakpoint{Kind: BreakOpcode, OpCode: 0x0c, Player: 2}},
+		{name: "cycle", bp: Breakpoint{Kind: BreakCycle, Cycle: 300}, hit: true, cycle: 300},
+		{name: "watch", bp: Breakpoint{Kind: BreakWatch, Addr: 24, Size: 1}, hit: true, player: 1, addr: 24},
+		{name: "watch untouched", bp: Breakpoint{Kind: BreakWatch, Addr: 3000, Size: 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cw := newTestCorewar(t, testConfig(t, forker, bomber))
+			id := cw.AddBreakpoint(tt.bp)
+			err := cw.RunUntil(context.Background(), 2000)
+			if !tt.hit {
+				if err != nil || cw.Cycle != 2000 {
+					t.Fatalf("Unexpected stop at cycle %d: %v.", cw.Cycle, err)
+				}
+				return
+			}
+			var breakErr *BreakError
+			if !errors.As(err, &breakErr) {
+				t.Fatalf("Unexpected error: %v, expected a *BreakError.", err)
+			}
+			hit := breakErr.Hits[0]
+			if hit.Breakpoint.ID != id || hit.Breakpoint.Kind != tt.bp.Kind {
+				t.Fatalf("Unexpected breakpoint hit: %s.", breakErr)
+			}
+			// The round of the hit is complete, the game stops right after it.
+			// The cycle breakpoints stop before the round of their cycle.
+			next := hit.Cycle + 1
+			if tt.bp.Kind == BreakCycle {
+				next = hit.Cycle
+			}
+			if cw.Cycle != next || tt.cycle != 0 && hit.Cycle != tt.cycle {
+				t.Fatalf("Hit at cycle %d, game at cycle %d, expected cycle %d.", hit.Cycle, cw.Cycle, tt.cycle)
+			}
+			if tt.player == 0 {
+				if hit.Process != nil {
+					t.Fatalf("Unexpected process in hit: %s.", breakErr)
+				}
+				return
+			}
+			if hit.Process == nil || hit.Process.Player.Number != tt.player || hit.Addr != tt.addr {
+				t.Fatalf("Unexpected hit: %s, expected player %d at 0x%04x.", breakErr, tt.player, tt.addr)
+			}
+		})
+	}
+}
+
+func TestBreakpointFork(t *testing.T) {
+	const spawner = `.name "spawner"
+.comment "forks ahead and waits"
+
+	ld	%0, r2
+	fork	%:far
+wait:	zjmp	%:wait
+far:	live	%1
+`
+	cw := newTestCorewar(t, testConfig(t, spawner, bomber))
+	// Only the child is ever on far, created there by the fork.
+	cw.AddBreakpoint(Breakpoint{Kind: BreakAddr, Addr: 13})
+	var breakErr *BreakError
+	if err := cw.RunUntil(context.Background(), 2000); !errors.As(err, &breakErr) {
+		t.Fatalf("Unexpected error: %v, expected a *BreakError.", err)
+	}
+	hit := breakErr.Hits[0]
+	if hit.Process == nil || hit.Process.ID != 3 || hit.Process.Player.Number != 1 || hit.Addr != 13 {
+		t.Fatalf("Unexpected hit: %s, expected the child of player 1 at 0x000d.", breakErr)
+	}
+	if cw.Cycle != hit.Cycle+1 {
+		t.Fatalf("Hit at cycle %d, game at cycle %d.", hit.Cycle, cw.Cycle)
+	}
+}
+
+func TestBreakpointsContinue(t *testing.T) {
+	cw := newTestCorewar(t, testConfig(t, forker, bomber))
+	// The bomber lives every 5 instructions.
+	id := cw.AddBreakpoint(Breakpoint{Kind: BreakOpcode, OpCode: 0x01, Player: 2})
+	cw.AddBreakpoint(Breakpoint{Kind: BreakCycle, Cycle: 1000})
+
+	var breakErr *BreakError
+	last := -1
+	for range 3 {
+		if err := cw.RunUntil(context.Background(), -1); !errors.As(err, &breakErr) {
+			t.Fatalf("Unexpected error: %v.", err)
+		}
+		if cw.Cycle <= last {
+			t.Fatalf("Stopped at cycle %d after cycle %d.", cw.Cycle, last)
+		}
+		last = cw.Cycle
+	}
+
+	if !cw.RemoveBreakpoint(id) {
+		t.Fatalf("Breakpoint %d not found.", id)
+	}
+	if cw.RemoveBreakpoint(id) {
+		t.Fatalf("Breakpoint %d removed twice.", id)
+	}
+	if err := cw.RunUntil(context.Background(), -1); !errors.As(err, &breakErr) || cw.Cycle != 1000 {
+		t.Fatalf("Unexpected stop at cycle %d: %v, expected the cycle breakpoint.", cw.Cycle, err)
+	}
+	if n := len(cw.Breakpoints()); n != 1 {
+		t.Fatalf("%d breakpoints left, expected 1.", n)
+	}
+}
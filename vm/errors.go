@@ -0,0 +1,32 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by NewCorewar, wrapped in a PlayerError when about a player.
+var (
+	ErrNoPlayers        = errors.New("no players")
+	ErrInvalidProgram   = errors.New("invalid program")
+	ErrChampionTooLarge = errors.New("champion too large")
+	ErrProgramTooLarge  = errors.New("program too large")
+	ErrOverlap          = errors.New("overlapping placement")
+	ErrDuplicateNumber  = errors.New("duplicate player number")
+)
+
+// PlayerError describes which player failed to load and why.
+type PlayerError struct {
+	Number int // Player number.
+	Index  int // Index of the player in Config.Players.
+	Err    error
+}
+
+func (e *PlayerError) Error() string {
+	return fmt.Sprintf("player %d (index %d): %s", e.Number, e.Index, e.Err)
+}
+
+func (e *PlayerError) Unwrap() error { return e.Err }
+
+// ErrNoHistory is returned when going back in time without Config.History.
+var ErrNoHistory = errors.New("no history")
@@ -0,0 +1,235 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// history keeps what is needed to go back in time: a snapshot every
+// few cycles, and since the last one, an undo log of each round.
+type history struct {
+	interval    int // Cycles between checkpoints.
+	checkpoints []checkpoint
+	undo        []undoEntry
+}
+
+type checkpoint struct {
+	cycle int
+	data  []byte // See MarshalBinary.
+}
+
+// undoEntry is the state at the start of a round, followed by the
+// processes as they were before their turn and the memory as it was
+// before each access during the round.
+type undoEntry struct {
+	nextPID        int
+	cycle          int
+	cyclesToDie    int
+	curCyclesToDie int
+	liveCalls      int
+	checks         int
+	lastAlive      *Player
+	winner         *Player
+	endReason      EndReason
+
+	players   []Player
+	processes []*Process // The list itself, see recordRound.
+
+	changed []processUndo
+	mem     []memUndo
+}
+
+// processUndo is the value of a process before its turn.
+type processUndo struct {
+	process *Process
+	value   Process
+}
+
+// memUndo is the content of a memory range before it got used.
+type memUndo struct {
+	addr  uint32
+	size  int
+	bytes [4]byte
+	cells [4]Cell // Only set when tracking ownership.
+}
+
+// recordRound is called at the start of each round, with the history enabled.
+func (cw *Corewar) recordRound() error {
+	h := cw.history
+	if len(h.checkpoints) == 0 || cw.Cycle >= h.checkpoints[len(h.checkpoints)-1].cycle+h.interval {
+		data, err := cw.MarshalBinary()
+		if err != nil {
+			return fmt.Errorf("checkpoint: %w", err)
+		}
+		h.checkpoints = append(h.checkpoints, checkpoint{cycle: cw.Cycle, data: data})
+		h.undo = h.undo[:0]
+	}
+
+	e := undoEntry{
+		nextPID:        cw.NextPID,
+		cycle:          cw.Cycle,
+		cyclesToDie:    cw.CyclesToDie,
+		curCyclesToDie: cw.CurCyclesToDie,
+		liveCalls:      cw.LiveCalls,
+		checks:         cw.Checks,
+		lastAlive:      cw.LastAlive,
+		winner:         cw.Winner,
+		endReason:      cw.EndReason,
+
+		players:   make([]Player, len(cw.Players)),
+		processes: cw.Processes,
+	}
+	for i, p := range cw.Players {
+		e.players[i] = *p
+	}
+	// The forks only append to the list, keeping it as is is enough,
+	// but the deaths remove the processes in place, work on a copy then.
+	if cw.CurCyclesToDie == 0 {
+		cw.Processes = slices.Clone(cw.Processes)
+	}
+	h.undo = append(h.undo, e)
+	return nil
+}
+
+// recordProcess saves the process before its turn changes it.
+func (cw *Corewar) recordProcess(p *Process) {
+	h := cw.history
+	if len(h.undo) == 0 {
+		return
+	}
+	e := &h.undo[len(h.undo)-1]
+	e.changed = append(e.changed, processUndo{process: p, value: *p})
+}
+
+// recordMemory saves the given memory range before it gets used.
+func (cw *Corewar) recordMemory(addr uint32, size int) {
+	h := cw.history
+	if len(h.undo) == 0 {
+		return
+	}
+	m := memUndo{addr: addr, size: size}
+	cw.Ram.Read(addr, m.bytes[:size])
+	if cw.Owners != nil {
+		for i := range size {
+			m.cells[i] = cw.Owners[(int(addr%uint32(len(cw.Owners)))+i)%len(cw.Owners)]
+		}
+	}
+	e := &h.undo[len(h.undo)-1]
+	e.mem = append(e.mem, m)
+}
+
+// revert puts the game back as it was at the start of the round.
+func (cw *Corewar) revert(e *undoEntry) {
+	for i := len(e.mem) - 1; i >= 0; i-- {
+		m := e.mem[i]
+		cw.Ram.Write(m.addr, m.bytes[:m.size])
+		if cw.Owners != nil {
+			for j := range m.size {
+				cw.Owners[(int(m.addr%uint32(len(cw.Owners)))+j)%len(cw.Owners)] = m.cells[j]
+			}
+		}
+	}
+	for i, p := range cw.Players {
+		*p = e.players[i]
+	}
+	// Undo NextCycle first, the processes waiting got their wait decreased.
+	if cycles := cw.Cycle - e.cycle; cycles > 0 {
+		for _, p := range cw.Processes {
+			if p.CurInstruction != nil {
+				p.WaitCycles += cycles
+			}
+		}
+	}
+	for i := len(e.changed) - 1; i >= 0; i-- {
+		*e.changed[i].process = e.changed[i].value
+	}
+	cw.Processes = e.processes
+
+	cw.NextPID = e.nextPID
+	cw.Cycle = e.cycle
+	cw.CyclesToDie = e.cyclesToDie
+	cw.CurCyclesToDie = e.curCyclesToDie
+	cw.LiveCalls = e.liveCalls
+	cw.Checks = e.checks
+	cw.LastAlive = e.lastAlive
+	cw.Winner = e.winner
+	cw.EndReason = e.endReason
+}
+
+// StepBack goes back n cycles. See SeekCycle.
+func (cw *Corewar) StepBack(n int) error {
+	return cw.SeekCycle(cw.Cycle - n)
+}
+
+// SeekCycle moves the game to the given cycle, stopping exactly on it.
+//
+// Going forward plays the rounds as RunUntil does, with the breakpoints
+// suspended so it doesn't stop short of the cycle.
+// Going back requires Config.History: the rounds since the last
+// checkpoint are undone, further than that, the closest checkpoint
+// is restored and the game replayed up to the cycle.
+// No event is sent while going back, the observer gets the whole
+// memory as changed with the next round.
+func (cw *Corewar) SeekCycle(cycle int) error {
+	if cycle >= cw.Cycle {
+		return cw.seek(cycle)
+	}
+	h := cw.history
+	if h == nil {
+		return ErrNoHistory
+	}
+	cycle = max(cycle, 0)
+	if len(h.checkpoints) == 0 || h.checkpoints[0].cycle > cycle {
+		return fmt.Errorf("%w: cycle %d is before the first checkpoint", ErrNoHistory, cycle)
+	}
+
+	if len(h.undo) > 0 && h.undo[0].cycle <= cycle {
+		// Undo the rounds down to the last one started before the cycle.
+		i := len(h.undo) - 1
+		for h.undo[i].cycle > cycle {
+			i--
+		}
+		for j := len(h.undo) - 1; j >= i; j-- {
+			cw.revert(&h.undo[j])
+		}
+		h.undo = h.undo[:i]
+	} else {
+		// Restore the closest checkpoint, it will be taken again when replaying.
+		i := len(h.checkpoints) - 1
+		for i > 0 && h.checkpoints[i].cycle > cycle {
+			i--
+		}
+		if err := cw.UnmarshalBinary(h.checkpoints[i].data); err != nil {
+			return fmt.Errorf("restore checkpoint at cycle %d: %w", h.checkpoints[i].cycle, err)
+		}
+		h.checkpoints = h.checkpoints[:i]
+		h.undo = h.undo[:0]
+		cw.history = h
+	}
+
+	// Replay up to the cycle without sending any event.
+	o := cw.Config.Observer
+	cw.Config.Observer = nil
+	err := cw.seek(cycle)
+	cw.Config.Observer = o
+	if o != nil {
+		cw.changes = append(cw.changes[:0], MemChange{Addr: 0, Size: len(cw.Ram), Access: AccessNone})
+	}
+	return err
+}
+
+// seek plays the rounds up to the cycle with the breakpoints suspended.
+// Reaching the end of the game is not an error.
+func (cw *Corewar) seek(cycle int) error {
+	breakpoints := cw.breakpoints
+	cw.breakpoints = nil
+	err := cw.RunUntil(context.Background(), cycle)
+	cw.breakpoints = breakpoints
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSeekCycle(t *testing.T) {
+	tests := []struct {
+		name      string
+		history   int
+		run       int   // Cycle to play up to before seeking.
+		seeks     []int // Cycles to seek to, in order.
+		ownership bool
+	}{
+		{name: "undo log", history: 1000, run: 900, seeks: []int{850, 600, 1, 0}},
+		{name: "checkpoints", history: 100, run: 5000, seeks: []int{4321, 2000, 2999, 150, 3500}},
+		{name: "deaths", history: 500, run: 7100, seeks: []int{7099, 3000, 6100, 5000, 7000}},
+		{name: "forward", history: 100, run: 1000, seeks: []int{500, 1000, 1500, 200}},
+		{name: "ownership", history: 100, run: 3000, seeks: []int{2950, 1234}, ownership: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t, forker, bomber, quitter)
+			game := cfg
+			game.History = tt.history
+			game.TrackOwnership = tt.ownership
+			cw := newTestCorewar(t, game)
+			if err := cw.RunUntil(context.Background(), tt.run); err != nil {
+				t.Fatalf("Failed to run until cycle %d: %s.", tt.run, err)
+			}
+			for _, cycle := range tt.seeks {
+				if err := cw.SeekCycle(cycle); err != nil {
+					t.Fatalf("Failed to seek cycle %d: %s.", cycle, err)
+				}
+				if cw.Cycle != cycle {
+					t.Fatalf("Seek cycle %d landed on cycle %d.", cycle, cw.Cycle)
+				}
+				if got, want := stateHash(t, cw), hashAt(t, cfg, cycle); got != want {
+					t.Fatalf("State at cycle %d differs from a new game: %s, expected %s.", cycle, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSeekCycleNoHistory(t *testing.T) {
+	cw := newTestCorewar(t, testConfig(t, forker, bomber))
+	if err := cw.RunUntil(context.Background(), 100); err != nil {
+		t.Fatalf("Failed to run: %s.", err)
+	}
+	if err := cw.StepBack(10); !errors.Is(err, ErrNoHistory) {
+		t.Fatalf("Unexpected error going back without history: %v.", err)
+	}
+}
+
+func TestSeekCycleBreakpoints(t *testing.T) {
+	cfg := testConfig(t, forker, bomber)
+	cfg.History = 100
+	cw := newTestCorewar(t, cfg)
+	// Each live of the forker moves its processes through 0x000e.
+	cw.AddBreakpoint(Breakpoint{Kind: BreakAddr, Addr: 0x000e})
+	cw.AddBreakpoint(Breakpoint{Kind: BreakWatch, Addr: 0, Size: 4096})
+	cw.AddBreakpoint(Breakpoint{Kind: BreakCycle, Cycle: 300})
+
+	for _, cycle := range []int{1000, 250, 600, 0} {
+		if err := cw.SeekCycle(cycle); err != nil {
+			t.Fatalf("Failed to seek cycle %d: %s.", cycle, err)
+		}
+		if cw.Cycle != cycle {
+			t.Fatalf("Seek cycle %d landed on cycle %d.", cycle, cw.Cycle)
+		}
+		if n := len(cw.Breakpoints()); n != 3 {
+			t.Fatalf("Seek cycle %d left %d breakpoints, expected 3.", cycle, n)
+		}
+	}
+	var breakErr *BreakError
+	if err := cw.RunUntil(context.Background(), 1000); !errors.As(err, &breakErr) {
+		t.Fatalf("Breakpoints not restored after seeking: %v.", err)
+	}
+}
@@ -0,0 +1,130 @@
+package vm
+
+import (
+	"go.creack.net/corewar/asm/parser"
+	"go.creack.net/corewar/op"
+)
+
+// Observer receives the events emitted by the VM while playing.
+//
+// Methods are called synchronously from Round, so implementations
+// should return quickly. Embed NopObserver to only implement a subset.
+// A nil Observer in the config disables the events altogether.
+type Observer interface {
+	OnCycle(CycleEvent)       // A new cycle started.
+	OnExec(ExecEvent)         // An instruction got executed, noop excluded.
+	OnMove(MoveEvent)         // A process moved past its instruction, noop excluded.
+	OnLive(LiveEvent)         // A 'live' instruction got executed.
+	OnWrite(WriteEvent)       // The memory got written.
+	OnFork(ForkEvent)         // A process got forked.
+	OnDisplay(DisplayEvent)   // An 'aff' instruction got executed.
+	OnDeath(DeathEvent)       // A player died.
+	OnCheck(CheckEvent)       // CyclesToDie expired and got reset.
+	OnRound(RoundEvent)       // A round ended.
+	OnGameOver(GameOverEvent) // The game ended.
+}
+
+// CycleEvent is sent for every cycle, including the ones
+// skipped over when no process has anything to do.
+type CycleEvent struct {
+	Cycle int
+}
+
+// ExecEvent is sent after executing an instruction, with its
+// parameters resolved.
+type ExecEvent struct {
+	Process     *Process
+	Instruction *parser.Instruction
+	PC          uint32 // Address of the instruction.
+
+	// Values is the value used for each parameter: the content of
+	// source registers, the number of target registers, the memory
+	// content for 'ld' and the signed parameter otherwise.
+	Values [op.MaxArgsNumber]int64
+	Offset int64 // Sum of the indexes for 'ldi', 'lldi' and 'sti'.
+	Target int64 // Address used by 'zjmp', 'ldi', 'lldi', 'sti', 'fork' and 'lfork', before wrapping around.
+	Jumped bool  // Set when 'zjmp' jumped.
+}
+
+// MoveEvent is sent when a process moves its PC past the instruction
+// it executed. Bytes are the skipped bytes, only valid during the call.
+type MoveEvent struct {
+	Process *Process
+	From    uint32
+	Bytes   []byte
+}
+
+// LiveEvent is sent when a process calls 'live'.
+// Player is nil when the target player is invalid or dead.
+type LiveEvent struct {
+	Process *Process
+	Target  int // Player number given to 'live'.
+	Player  *Player
+}
+
+// WriteEvent is sent when a process writes to the memory.
+type WriteEvent struct {
+	Process *Process
+	Addr    uint32
+	Size    int
+	Value   uint32
+}
+
+// ForkEvent is sent when a process forks.
+type ForkEvent struct {
+	Parent *Process
+	Child  *Process
+	Long   bool // Set for 'lfork'.
+}
+
+// DisplayEvent is sent when a process calls 'aff'.
+type DisplayEvent struct {
+	Process *Process
+	Char    byte
+}
+
+// DeathEvent is sent when a player is declared dead.
+type DeathEvent struct {
+	Player      *Player
+	Processes   []*Process // Processes removed from the arena.
+	Cycle       int
+	CyclesToDie int // CyclesToDie in effect for the expired window.
+}
+
+// CheckEvent is sent when CyclesToDie expires, after the deaths.
+type CheckEvent struct {
+	Cycle       int
+	CyclesToDie int  // New CyclesToDie.
+	Decreased   bool // Set when CyclesToDie got decreased.
+}
+
+// RoundEvent is sent at the end of each round with the memory
+// changes that happened during it, in order. The first round also
+// includes the champions being loaded.
+// The changes slice is reused, it must not be retained after the call.
+type RoundEvent struct {
+	Cycle   int
+	Changes []MemChange
+}
+
+// GameOverEvent is sent once when the game ends.
+type GameOverEvent struct {
+	Reason EndReason
+	Winner *Player   // Last player reported alive.
+	Alive  []*Player // Players still alive when the game ended.
+}
+
+// NopObserver implements Observer and ignores all the events.
+type NopObserver struct{}
+
+func (NopObserver) OnCycle(CycleEvent)       {}
+func (NopObserver) OnExec(ExecEvent)         {}
+func (NopObserver) OnMove(MoveEvent)         {}
+func (NopObserver) OnLive(LiveEvent)         {}
+func (NopObserver) OnWrite(WriteEvent)       {}
+func (NopObserver) OnFork(ForkEvent)         {}
+func (NopObserver) OnDisplay(DisplayEvent)   {}
+func (NopObserver) OnDeath(DeathEvent)       {}
+func (NopObserver) OnCheck(CheckEvent)       {}
+func (NopObserver) OnRound(RoundEvent)       {}
+func (NopObserver) OnGameOver(GameOverEvent) {}
@@ -0,0 +1,115 @@
+package vm
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// recordObserver keeps the events it receives.
+type recordObserver struct {
+	NopObserver
+	execs    int
+	lives    []LiveEvent
+	writes   []WriteEvent
+	forks    []ForkEvent
+	deaths   []DeathEvent
+	gameOver []GameOverEvent
+	rounds   []RoundEvent
+}
+
+func (o *recordObserver) OnExec(ExecEvent)           { o.execs++ }
+func (o *recordObserver) OnLive(e LiveEvent)         { o.lives = append(o.lives, e) }
+func (o *recordObserver) OnWrite(e WriteEvent)       { o.writes = append(o.writes, e) }
+func (o *recordObserver) OnFork(e ForkEvent)         { o.forks = append(o.forks, e) }
+func (o *recordObserver) OnDeath(e DeathEvent)       { o.deaths = append(o.deaths, e) }
+func (o *recordObserver) OnGameOver(e GameOverEvent) { o.gameOver = append(o.gameOver, e) }
+
+func (o *recordObserver) OnRound(e RoundEvent) {
+	e.Changes = slices.Clone(e.Changes) // Reused by the VM.
+	o.rounds = append(o.rounds, e)
+}
+
+func TestObserver(t *testing.T) {
+	o := &recordObserver{}
+	cfg := testConfig(t, forker, quitter)
+	cfg.Observer = o
+	if _, err := Run(context.Background(), cfg); err != nil {
+		t.Fatalf("Failed to run: %s.", err)
+	}
+
+	if o.execs == 0 {
+		t.Fatal("No instruction executed.")
+	}
+	for _, e := range o.lives {
+		if e.Player == nil || e.Player.Number != e.Process.Player.Number {
+			t.Fatalf("Process of player %d reported a live for %+v.", e.Process.Player.Number, e.Player)
+		}
+	}
+	if len(o.forks) != 2 {
+		t.Fatalf("Got %d forks, expected 2.", len(o.forks))
+	}
+	for _, e := range o.forks {
+		if e.Long || e.Parent.Player.Number != 1 || e.Child.Player != e.Parent.Player || e.Child.ID == e.Parent.ID {
+			t.Fatalf("Unexpected fork: long %t from process %d of player %d to %d.", e.Long, e.Parent.ID, e.Parent.Player.Number, e.Child.ID)
+		}
+	}
+	for _, e := range o.writes {
+		if e.Size != 4 || e.Addr >= uint32(cfg.MemSize) {
+			t.Fatalf("Unexpected write of %d bytes at %d.", e.Size, e.Addr)
+		}
+	}
+	if len(o.deaths) != 1 || o.deaths[0].Player.Number != 2 {
+		t.Fatalf("Unexpected deaths: %+v, expected player 2.", o.deaths)
+	}
+	if len(o.gameOver) != 1 {
+		t.Fatalf("Got %d game over events, expected 1.", len(o.gameOver))
+	}
+	if e := o.gameOver[0]; e.Reason != EndLastPlayer || e.Winner.Number != 1 || len(e.Alive) != 1 || e.Alive[0].Number != 1 {
+		t.Fatalf("Unexpected game over: %+v.", e)
+	}
+}
+
+func TestRoundChanges(t *testing.T) {
+	o := &recordObserver{}
+	cfg := testConfig(t, forker, quitter)
+	cfg.Observer = o
+	cw := newTestCorewar(t, cfg)
+	for range 100 {
+		if err := cw.Round(); err != nil {
+			t.Fatalf("Failed to play round at cycle %d: %s.", cw.Cycle, err)
+		}
+	}
+	if len(o.rounds) != 100 {
+		t.Fatalf("Got %d rounds, expected 100.", len(o.rounds))
+	}
+
+	// The first round starts with the champions being loaded.
+	loaded := o.rounds[0].Changes[:2]
+	for i, want := range []MemChange{{Addr: 0, Size: 45}, {Addr: 2048, Size: 22}} {
+		if c := loaded[i]; c.Addr != want.Addr || c.Size != want.Size || c.Access != AccessNone || c.Process.Player.Number != i+1 {
+			t.Fatalf("Unexpected load of player %d: %+v.", i+1, c)
+		}
+	}
+
+	// Each write is in the changes of its round.
+	var writes []MemChange
+	for i, e := range o.rounds {
+		if i > 0 && e.Cycle <= o.rounds[i-1].Cycle {
+			t.Fatalf("Round at cycle %d after cycle %d.", e.Cycle, o.rounds[i-1].Cycle)
+		}
+		for _, c := range e.Changes {
+			if c.Access == AccessWrite {
+				writes = append(writes, c)
+			}
+		}
+	}
+	if len(writes) == 0 || len(writes) != len(o.writes) {
+		t.Fatalf("Got %d write changes for %d writes.", len(writes), len(o.writes))
+	}
+	for i, c := range writes {
+		if w := o.writes[i]; c.Addr != w.Addr || c.Size != w.Size || c.Process != w.Process {
+			t.Fatalf("Write change %+v does not match the write %+v.", c, w)
+		}
+	}
+}
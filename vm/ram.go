@@ -1,62 +1,111 @@
 package vm
 
-import "go.creack.net/corewar/op"
+import (
+	"slices"
 
-type Ram []RamEntry
+	"go.creack.net/corewar/op"
+)
 
-func (r Ram) Bytes(addr uint32, size int) []byte {
-	out := make([]byte, size)
-	for i := range size {
-		out[i] = r[(int(addr)+i)%len(r)].Value
+// Ram is the core memory. All addresses wrap around its size.
+type Ram []byte
+
+// Byte returns the byte at the given address.
+func (r Ram) Byte(addr uint32) byte {
+	return r[addr%uint32(len(r))]
+}
+
+// Read copies len(buf) bytes starting at addr into buf.
+func (r Ram) Read(addr uint32, buf []byte) {
+	start := int(addr % uint32(len(r)))
+	for n := 0; n < len(buf); {
+		n += copy(buf[n:], r[start:])
+		start = 0
 	}
-	return out
 }
 
-func (r Ram) GetRamValue32(p *Process, addr uint32) uint32 {
-	b := make([]byte, 4)
-	for i := range 4 {
-		b[i] = r[(int(addr)+i)%len(r)].Value
-		r[(int(addr)+i)%len(r)].Process = p
-		r[(int(addr)+i)%len(r)].AccessType = 2
+// Write copies buf into the memory starting at addr.
+func (r Ram) Write(addr uint32, buf []byte) {
+	start := int(addr % uint32(len(r)))
+	for n := 0; n < len(buf); {
+		n += copy(r[start:], buf[n:])
+		start = 0
 	}
-	return op.Endian.Uint32(b)
 }
 
-func (r Ram) GetRamValue16(p *Process, addr uint32) uint16 {
-	b := make([]byte, 2)
-	for i := range 2 {
-		b[i] = r[(int(addr)+i)%len(r)].Value
-		r[(int(addr)+i)%len(r)].Process = p
-		r[(int(addr)+i)%len(r)].AccessType = 3
+// Read32 reads a 4 bytes value.
+func (r Ram) Read32(addr uint32) uint32 {
+	start := int(addr % uint32(len(r)))
+	if start+4 <= len(r) {
+		return op.Endian.Uint32(r[start:])
 	}
-	return op.Endian.Uint16(b)
+	return uint32(r.Byte(addr))<<24 | uint32(r.Byte(addr+1))<<16 | uint32(r.Byte(addr+2))<<8 | uint32(r.Byte(addr+3))
 }
 
-// func (r Ram) GetRamValueIndex(p *Process, pc uint32, idx int16, mod int64) uint16 {
-// 	start := int64(pc) + (int64(idx) % mod)
+// Read16 reads a 2 bytes value.
+func (r Ram) Read16(addr uint32) uint16 {
+	start := int(addr % uint32(len(r)))
+	if start+2 <= len(r) {
+		return op.Endian.Uint16(r[start:])
+	}
+	return uint16(r.Byte(addr))<<8 | uint16(r.Byte(addr+1))
+}
 
-// 	b := make([]byte, 2)
-// 	for i := range 2 {
-// 		b[i] = r[(int64(i)+start)%int64(len(r))].Value
-// 		r[(int64(i)+start)%int64(len(r))].Process = p
-// 		r[(int64(i)+start)%int64(len(r))].AccessType = 2
-// 	}
+// Write32 stores a 4 bytes value.
+func (r Ram) Write32(addr, value uint32) {
+	start := int(addr % uint32(len(r)))
+	if start+4 <= len(r) {
+		op.Endian.PutUint32(r[start:], value)
+		return
+	}
+	for i := range 4 {
+		r[(start+i)%len(r)] = byte(value >> (8 * (3 - i)))
+	}
+}
 
-// 	return op.Endian.Uint16(b)
-// }
+// Clone returns a copy of the memory.
+func (r Ram) Clone() Ram {
+	return slices.Clone(r)
+}
 
-func (r Ram) SetRamValue(p *Process, addr, value uint32) {
-	b := make([]byte, 4)
-	op.Endian.PutUint32(b, value)
-	for i := range 4 {
-		r[(int(addr)+i)%len(r)].Value = b[i]
-		r[(int(addr)+i)%len(r)].Process = p
-		r[(int(addr)+i)%len(r)].AccessType = 1
+// AccessType is how a memory cell was last used.
+type AccessType int
+
+// Available access types.
+const (
+	AccessNone      AccessType = iota // Loaded or untouched.
+	AccessWrite                       // Written by 'st' or 'sti'.
+	AccessRead                        // Read as a 4 bytes value.
+	AccessReadIndex                   // Read as a 2 bytes index.
+)
+
+// Cell is the ownership metadata of a memory cell.
+type Cell struct {
+	Player int // Number of the last player to use the cell, 0 if none.
+	Access AccessType
+}
+
+// Ownership tracks who last used each memory cell.
+// Same size as the Ram, addresses wrap around.
+type Ownership []Cell
+
+// Set marks the given range as used by the player.
+func (o Ownership) Set(addr uint32, size, player int, access AccessType) {
+	start := int(addr % uint32(len(o)))
+	for i := range size {
+		o[(start+i)%len(o)] = Cell{Player: player, Access: access}
 	}
 }
 
-type RamEntry struct {
-	Value      byte
-	Process    *Process // Who last used the entry.
-	AccessType int
+// Clone returns a copy of the ownership metadata.
+func (o Ownership) Clone() Ownership {
+	return slices.Clone(o)
+}
+
+// MemChange is a range of memory used by a process.
+// The current values can be read from the Ram.
+type MemChange struct {
+	Addr    uint32 // Start address, may wrap around the end of the memory.
+	Size    int
+	Process *Process
+	Access  AccessType
 }
@@ -0,0 +1,93 @@
+package vm
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestRam(t *testing.T) {
+	tests := []struct {
+		name string
+		addr uint32
+		data []byte
+		want Ram // Memory after the write.
+	}{
+		{name: "start", addr: 0, data: []byte{1, 2}, want: Ram{1, 2, 0, 0, 0, 0, 0, 0}},
+		{name: "end", addr: 6, data: []byte{1, 2}, want: Ram{0, 0, 0, 0, 0, 0, 1, 2}},
+		{name: "wrap", addr: 6, data: []byte{1, 2, 3, 4}, want: Ram{3, 4, 0, 0, 0, 0, 1, 2}},
+		{name: "out of bounds", addr: 13, data: []byte{1, 2, 3, 4}, want: Ram{4, 0, 0, 0, 0, 1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := make(Ram, 8)
+			r.Write(tt.addr, tt.data)
+			if !bytes.Equal(r, tt.want) {
+				t.Fatalf("Unexpected memory after write: %v, expected %v.", r, tt.want)
+			}
+			buf := make([]byte, len(tt.data))
+			r.Read(tt.addr, buf)
+			if !bytes.Equal(buf, tt.data) {
+				t.Fatalf("Unexpected read: %v, expected %v.", buf, tt.data)
+			}
+			if len(tt.data) == 4 {
+				if got, want := r.Read32(tt.addr), uint32(0x01020304); got != want {
+					t.Fatalf("Unexpected 32 bits read: 0x%08x, expected 0x%08x.", got, want)
+				}
+				r2 := make(Ram, 8)
+				r2.Write32(tt.addr, 0x01020304)
+				if !bytes.Equal(r2, tt.want) {
+					t.Fatalf("Unexpected memory after 32 bits write: %v, expected %v.", r2, tt.want)
+				}
+			}
+			if got, want := r.Read16(tt.addr), uint16(0x0102); got != want {
+				t.Fatalf("Unexpected 16 bits read: 0x%04x, expected 0x%04x.", got, want)
+			}
+		})
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	cfg := testConfig(t, forker, bomber)
+	cw := newTestCorewar(t, cfg)
+	if cw.Owners != nil {
+		t.Fatal("Ownership tracked without TrackOwnership.")
+	}
+
+	cfg.TrackOwnership = true
+	cw = newTestCorewar(t, cfg)
+	if len(cw.Owners) != len(cw.Ram) {
+		t.Fatalf("Ownership of %d cells, expected %d.", len(cw.Owners), len(cw.Ram))
+	}
+	for _, p := range cw.Processes {
+		if c := cw.Owners[p.PC]; c.Player != p.Player.Number || c.Access != AccessNone {
+			t.Fatalf("Load address of player %d owned by %+v.", p.Player.Number, c)
+		}
+	}
+	if c := cw.Owners[len(cw.Owners)-1]; c.Player != 0 {
+		t.Fatalf("Untouched cell owned by player %d.", c.Player)
+	}
+
+	// The forker stores r1 in its own code first.
+	for cw.Cycle < 100 {
+		if err := cw.Round(); err != nil {
+			t.Fatalf("Failed to play round at cycle %d: %s.", cw.Cycle, err)
+		}
+	}
+	found := false
+	for _, c := range cw.Owners {
+		if c.Player == 1 && c.Access == AccessWrite {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatal("No cell written by player 1.")
+	}
+
+	o := make(Ownership, 4)
+	o.Set(3, 2, 2, AccessRead)
+	if want := (Ownership{{Player: 2, Access: AccessRead}, {}, {}, {Player: 2, Access: AccessRead}}); !slices.Equal(o, want) {
+		t.Fatalf("Unexpected ownership after a wrapping set: %v, expected %v.", o, want)
+	}
+}
@@ -0,0 +1,185 @@
+package vm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultReplayInterval is the default number of cycles between replay hashes.
+const DefaultReplayInterval = 1000
+
+const replayVersion = 1
+
+// ErrReplayMismatch is returned when a replay doesn't play as recorded.
+var ErrReplayMismatch = errors.New("replay mismatch")
+
+// Replay is everything needed to play a game again, bit for bit,
+// with the hash of the state every Interval cycles to verify it.
+// Meant to be stored as JSON.
+type Replay struct {
+	Version   int
+	Config    Config       // Players sorted by number, as loaded.
+	Placement []uint32     // Load address of each player, same order as Config.Players.
+	Interval  int          // Cycles between hashes.
+	Hashes    []ReplayHash // In order, the last one is the end of the game.
+	Result    Result
+
+	next int // Index of the next hash to verify.
+}
+
+// ReplayHash is the state hash at a given cycle, see StateHash.
+type ReplayHash struct {
+	Cycle int
+	Hash  string
+}
+
+// StateHash returns the hex encoded sha256 of the game state.
+// The ownership layer is not part of it, it doesn't change the game.
+func (cw *Corewar) StateHash() (string, error) {
+	tmp := *cw
+	tmp.Owners = nil
+	tmp.Config.TrackOwnership = false
+	data, err := tmp.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// Record plays a full game and records it, hashing the state every interval cycles.
+// Events are sent to cfg.Observer if set.
+func Record(ctx context.Context, cfg Config, interval int) (*Replay, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid replay interval %d", interval)
+	}
+	cw, err := NewCorewar(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Replay{
+		Version:  replayVersion,
+		Config:   cw.Config,
+		Interval: interval,
+	}
+	r.Config.Observer = nil
+	r.Config.TrackOwnership = false
+	r.Config.History = 0
+	for _, p := range cw.Processes {
+		r.Placement = append(r.Placement, p.PC)
+	}
+
+	for {
+		next := (cw.Cycle/interval + 1) * interval
+		err := cw.RunUntil(ctx, next)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		hash, herr := cw.StateHash()
+		if herr != nil {
+			return nil, fmt.Errorf("hash at cycle %d: %w", cw.Cycle, herr)
+		}
+		r.Hashes = append(r.Hashes, ReplayHash{Cycle: cw.Cycle, Hash: hash})
+		if err != nil {
+			break
+		}
+	}
+	r.Result = cw.Result()
+	return r, nil
+}
+
+// NewCorewar creates the game to replay and checks the placement.
+// Config.Observer and Config.TrackOwnership can be set before to watch
+// the replay, they don't change the hashes.
+func (r *Replay) NewCorewar() (*Corewar, error) {
+	if r.Version != replayVersion {
+		return nil, fmt.Errorf("unsupported replay version %d, expect %d", r.Version, replayVersion)
+	}
+	if r.Interval <= 0 {
+		return nil, fmt.Errorf("invalid replay interval %d", r.Interval)
+	}
+	cw, err := NewCorewar(r.Config)
+	if err != nil {
+		return nil, err
+	}
+	if len(cw.Processes) != len(r.Placement) {
+		return nil, fmt.Errorf("%w: %d players, expect %d", ErrReplayMismatch, len(cw.Processes), len(r.Placement))
+	}
+	for i, p := range cw.Processes {
+		if p.PC != r.Placement[i] {
+			return nil, fmt.Errorf("%w: player %d loaded at %d, expect %d", ErrReplayMismatch, p.Player.Number, p.PC, r.Placement[i])
+		}
+	}
+	r.next = 0
+	return cw, nil
+}
+
+// Step plays the next round of a game created with NewCorewar,
+// without going past the next hash, and verifies it when reached.
+// Returns io.EOF when the game is over.
+func (r *Replay) Step(ctx context.Context, cw *Corewar) error {
+	if err := ctx.Err(); err != nil {
+		cw.EndReason = EndCanceled
+		return err
+	}
+
+	next := (cw.Cycle/r.Interval + 1) * r.Interval
+	cw.stopCycle = next
+	err := cw.Round()
+	cw.stopCycle = 0
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("round at cycle %d: %w", cw.Cycle, err)
+	}
+	if err != nil || cw.Cycle == next {
+		if verr := r.verify(cw); verr != nil {
+			return verr
+		}
+	}
+	return err
+}
+
+// verify compares the state with the next recorded hash.
+func (r *Replay) verify(cw *Corewar) error {
+	if r.next >= len(r.Hashes) {
+		return fmt.Errorf("%w: game still running at cycle %d", ErrReplayMismatch, cw.Cycle)
+	}
+	expect := r.Hashes[r.next]
+	r.next++
+	if cw.Cycle != expect.Cycle {
+		return fmt.Errorf("%w: reached cycle %d, expect %d", ErrReplayMismatch, cw.Cycle, expect.Cycle)
+	}
+	hash, err := cw.StateHash()
+	if err != nil {
+		return fmt.Errorf("hash at cycle %d: %w", cw.Cycle, err)
+	}
+	if hash != expect.Hash {
+		return fmt.Errorf("%w: state hash at cycle %d is %s, expect %s", ErrReplayMismatch, cw.Cycle, hash, expect.Hash)
+	}
+	return nil
+}
+
+// Play replays the whole game and verifies every hash.
+// Events are sent to Config.Observer if set.
+func (r *Replay) Play(ctx context.Context) (Result, error) {
+	cw, err := r.NewCorewar()
+	if err != nil {
+		return Result{}, err
+	}
+	for {
+		if err := r.Step(ctx, cw); err != nil {
+			if !errors.Is(err, io.EOF) {
+				return cw.Result(), err
+			}
+			break
+		}
+	}
+	if r.next != len(r.Hashes) {
+		return cw.Result(), fmt.Errorf("%w: game over at cycle %d, expect %d", ErrReplayMismatch, cw.Cycle, r.Hashes[len(r.Hashes)-1].Cycle)
+	}
+	return cw.Result(), nil
+}
@@ -0,0 +1,71 @@
+package vm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// record records a game between the test champions, through JSON like the replay files.
+func record(t *testing.T, interval int) *Replay {
+	t.Helper()
+	r, err := Record(context.Background(), testConfig(t, forker, bomber, quitter), interval)
+	if err != nil {
+		t.Fatalf("Failed to record: %s.", err)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Failed to encode the replay: %s.", err)
+	}
+	var out Replay
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Failed to decode the replay: %s.", err)
+	}
+	return &out
+}
+
+func TestReplay(t *testing.T) {
+	for _, interval := range []int{7, 100, DefaultReplayInterval, 1 << 20} {
+		r := record(t, interval)
+		res, err := r.Play(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to replay with interval %d: %s.", interval, err)
+		}
+		if res.Winner != r.Result.Winner || res.Cycle != r.Result.Cycle || res.Reason != r.Result.Reason {
+			t.Fatalf("Replay with interval %d ended with %+v, expected %+v.", interval, res, r.Result)
+		}
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		tamper   func(r *Replay)
+		mismatch bool // Expect ErrReplayMismatch, any error otherwise.
+	}{
+		{name: "hash", tamper: func(r *Replay) { r.Hashes[2].Hash = r.Hashes[1].Hash }, mismatch: true},
+		{name: "cycle", tamper: func(r *Replay) { r.Hashes[len(r.Hashes)-1].Cycle++ }, mismatch: true},
+		{name: "missing end", tamper: func(r *Replay) { r.Hashes = r.Hashes[:len(r.Hashes)-1] }, mismatch: true},
+		{name: "extra hash", tamper: func(r *Replay) { r.Hashes = append(r.Hashes, r.Hashes[len(r.Hashes)-1]) }, mismatch: true},
+		{name: "placement", tamper: func(r *Replay) { r.Placement[1]++ }, mismatch: true},
+		{name: "players", tamper: func(r *Replay) { r.Placement = r.Placement[:2] }, mismatch: true},
+		{name: "program", tamper: func(r *Replay) { r.Config.Players[1].Data = r.Config.Players[0].Data }, mismatch: true},
+		{name: "config", tamper: func(r *Replay) { r.Config.CycleDelta++ }, mismatch: true},
+		{name: "version", tamper: func(r *Replay) { r.Version++ }},
+		{name: "interval", tamper: func(r *Replay) { r.Interval = 0 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := record(t, 500)
+			tt.tamper(r)
+			_, err := r.Play(context.Background())
+			switch {
+			case err == nil:
+				t.Fatal("Tampered replay played without error.")
+			case tt.mismatch && !errors.Is(err, ErrReplayMismatch):
+				t.Fatalf("Unexpected error: %s, expected %s.", err, ErrReplayMismatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// EndReason describes why a game ended.
+type EndReason int
+
+// Available end reasons.
+const (
+	EndNone        EndReason = iota // The game is still running.
+	EndLastPlayer                   // At most one player is still alive.
+	EndNoProcess                    // At most one process is left in the arena.
+	EndCyclesToDie                  // CyclesToDie reached 0.
+	EndCanceled                     // The game was interrupted by the caller.
+)
+
+func (r EndReason) String() string {
+	switch r {
+	case EndNone:
+		return "running"
+	case EndLastPlayer:
+		return "last player alive"
+	case EndNoProcess:
+		return "no process left"
+	case EndCyclesToDie:
+		return "cycles to die exhausted"
+	case EndCanceled:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
+// PlayerResult is the final state of a player.
+type PlayerResult struct {
+	Number        int
+	Name          string
+	Dead          bool
+	TotalLives    int // Total number of 'live' calls for the player.
+	LastLiveCycle int // Cycle of the last valid 'live' call for the player.
+	ProcessCount  int // Number of processes when the game ended.
+}
+
+// Result is the outcome of a game.
+type Result struct {
+	Winner  int // Number of the winning player, 0 while running.
+	Cycle   int // Cycle at which the game ended.
+	Reason  EndReason
+	Players []PlayerResult
+}
+
+// Result returns the current state of the game as a Result.
+// Can be called at any time, the winner is only set once the game ended.
+func (cw *Corewar) Result() Result {
+	res := Result{
+		Cycle:   cw.Cycle,
+		Reason:  cw.EndReason,
+		Players: make([]PlayerResult, 0, len(cw.Players)),
+	}
+	if cw.Winner != nil {
+		res.Winner = cw.Winner.Number
+	}
+
+	for _, p := range cw.Players {
+		res.Players = append(res.Players, PlayerResult{
+			Number:        p.Number,
+			Name:          p.Name,
+			Dead:          p.Dead,
+			TotalLives:    p.TotalLives,
+			LastLiveCycle: p.LastLiveCycle,
+			ProcessCount:  p.ProcessCount,
+		})
+	}
+	return res
+}
+
+// Run plays a full game headlessly with the given config.
+// Returns when the game is over or when the context is done.
+// Events are sent to cfg.Observer if set.
+func Run(ctx context.Context, cfg Config) (Result, error) {
+	cw, err := NewCorewar(cfg)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := cw.RunUntil(ctx, -1); err != nil && !errors.Is(err, io.EOF) {
+		return cw.Result(), err
+	}
+	return cw.Result(), nil
+}
+
+// RunUntil plays rounds until the given cycle is reached, stopping
+// exactly on it. A negative cycle plays until the game is over.
+// Returns io.EOF if the game ended before reaching the cycle,
+// or a *BreakError if breakpoints got triggered.
+func (cw *Corewar) RunUntil(ctx context.Context, cycle int) error {
+	cw.stopCycle = cycle
+	defer func() { cw.stopCycle = 0 }()
+
+	for cycle < 0 || cw.Cycle < cycle {
+		select {
+		case <-ctx.Done():
+			cw.EndReason = EndCanceled
+			return ctx.Err()
+		default:
+		}
+		if err := cw.Round(); err != nil {
+			var breakErr *BreakError
+			if errors.Is(err, io.EOF) || errors.As(err, &breakErr) {
+				return err
+			}
+			return fmt.Errorf("round at cycle %d: %w", cw.Cycle, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		srcs   []string
+		winner int
+		reason EndReason
+		dead   []bool // Per player.
+	}{
+		{name: "quitter dies", srcs: []string{forker, quitter}, winner: 1, reason: EndLastPlayer, dead: []bool{false, true}},
+		{name: "one process left", srcs: []string{quitter, bomber}, winner: 2, reason: EndNoProcess, dead: []bool{true, false}},
+		{name: "cycles to die", srcs: []string{forker, forker}, winner: 2, reason: EndCyclesToDie, dead: []bool{false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Run(context.Background(), testConfig(t, tt.srcs...))
+			if err != nil {
+				t.Fatalf("Failed to run: %s.", err)
+			}
+			if res.Winner != tt.winner || res.Reason != tt.reason {
+				t.Fatalf("Player %d won with %q, expected player %d with %q.", res.Winner, res.Reason, tt.winner, tt.reason)
+			}
+			for i, p := range res.Players {
+				if p.Dead != tt.dead[i] {
+					t.Errorf("Player %d dead: %t, expected %t.", p.Number, p.Dead, tt.dead[i])
+				}
+				// The winner is the last player reported alive.
+				if w := res.Players[tt.winner-1]; p.LastLiveCycle > w.LastLiveCycle {
+					t.Errorf("Player %d lived at cycle %d, after the winner at %d.", p.Number, p.LastLiveCycle, w.LastLiveCycle)
+				}
+			}
+		})
+	}
+}
+
+func TestRunNoLive(t *testing.T) {
+	const idle = `.name "idle"
+.comment "never lives"
+
+	zjmp	%0
+`
+	res, err := Run(context.Background(), testConfig(t, idle, idle, idle))
+	if err != nil {
+		t.Fatalf("Failed to run: %s.", err)
+	}
+	// Without any 'live', the last player wins.
+	if res.Winner != 3 {
+		t.Fatalf("Player %d won, expected player 3.", res.Winner)
+	}
+}
+
+func TestRunUntil(t *testing.T) {
+	cw := newTestCorewar(t, testConfig(t, forker, quitter))
+	// Processes wait between instructions, the cycles are still reached exactly.
+	for _, cycle := range []int{0, 1, 7, 26, 1000} {
+		if err := cw.RunUntil(context.Background(), cycle); err != nil {
+			t.Fatalf("Failed to run until cycle %d: %s.", cycle, err)
+		}
+		if cw.Cycle != cycle {
+			t.Fatalf("Stopped at cycle %d, expected %d.", cw.Cycle, cycle)
+		}
+	}
+	if err := cw.RunUntil(context.Background(), 1<<30); !errors.Is(err, io.EOF) {
+		t.Fatalf("Unexpected error at the end of the game: %v.", err)
+	}
+	if cw.EndReason != EndLastPlayer || cw.Cycle >= 1<<30 {
+		t.Fatalf("Game ended at cycle %d with %q.", cw.Cycle, cw.EndReason)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err := Run(ctx, testConfig(t, forker, bomber))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Unexpected error: %v.", err)
+	}
+	if res.Reason != EndCanceled || res.Winner != 0 {
+		t.Fatalf("Unexpected result for a canceled game: %+v.", res)
+	}
+}
+
+func TestRunNoPlayers(t *testing.T) {
+	if _, err := Run(context.Background(), testConfig(t)); !errors.Is(err, ErrNoPlayers) {
+		t.Fatalf("Unexpected error: %v.", err)
+	}
+}
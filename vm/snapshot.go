@@ -0,0 +1,367 @@
+package vm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"go.creack.net/corewar/asm/parser"
+	"go.creack.net/corewar/op"
+)
+
+// Snapshot format.
+//
+// A snapshot starts with the magic and the version, followed by the
+// config, the memory, the ownership, the players, the processes and
+// the counters. Integers are varints, booleans a single byte.
+// Pointers to players are stored as their index in the player list, -1 for nil.
+// The current instruction of a process is stored as its encoded bytes.
+const (
+	snapshotMagic   = "CWSS"
+	snapshotVersion = 1
+)
+
+// ErrInvalidSnapshot is returned when restoring a malformed snapshot.
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// snapshotWriter appends values to a snapshot.
+type snapshotWriter struct {
+	buf []byte
+}
+
+func (w *snapshotWriter) int(v int)       { w.buf = binary.AppendVarint(w.buf, int64(v)) }
+func (w *snapshotWriter) uint(v uint64)   { w.buf = binary.AppendUvarint(w.buf, v) }
+func (w *snapshotWriter) bytes(b []byte)  { w.int(len(b)); w.buf = append(w.buf, b...) }
+func (w *snapshotWriter) string(s string) { w.bytes([]byte(s)) }
+
+func (w *snapshotWriter) bool(v bool) {
+	if v {
+		w.buf = append(w.buf, 1)
+		return
+	}
+	w.buf = append(w.buf, 0)
+}
+
+// snapshotReader reads values from a snapshot.
+// The first error is kept and makes all the subsequent reads return zero values.
+type snapshotReader struct {
+	buf []byte
+	err error
+}
+
+func (r *snapshotReader) fail(format string, args ...any) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: "+format, append([]any{ErrInvalidSnapshot}, args...)...)
+	}
+}
+
+func (r *snapshotReader) int() int {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.fail("truncated integer")
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return int(v)
+}
+
+func (r *snapshotReader) uint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.fail("truncated integer")
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *snapshotReader) bytes() []byte {
+	n := r.int()
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.buf) {
+		r.fail("truncated data")
+		return nil
+	}
+	out := make([]byte, n)
+	copy(out, r.buf)
+	r.buf = r.buf[n:]
+	return out
+}
+
+func (r *snapshotReader) string() string { return string(r.bytes()) }
+
+func (r *snapshotReader) bool() bool {
+	if r.err != nil {
+		return false
+	}
+	if len(r.buf) == 0 {
+		r.fail("truncated boolean")
+		return false
+	}
+	v := r.buf[0]
+	r.buf = r.buf[1:]
+	return v != 0
+}
+
+// count reads a length and makes sure it is within [0, limit].
+func (r *snapshotReader) count(limit int) int {
+	n := r.int()
+	if n < 0 || n > limit {
+		r.fail("invalid count %d", n)
+		return 0
+	}
+	return n
+}
+
+// encodeInstruction encodes back a decoded instruction.
+func encodeInstruction(ins *parser.Instruction) []byte {
+	buf := []byte{ins.OpCode.Code}
+	if ins.OpCode.EncodingByte {
+		buf = append(buf, ins.ParamsEncoding())
+	}
+	for _, elem := range ins.Params {
+		switch {
+		case elem.Typ == op.TReg:
+			buf = append(buf, byte(elem.Value))
+		case ins.OpCode.ParamMode == op.ParamModeDynamic && elem.Typ == op.TDir:
+			buf = op.Endian.AppendUint32(buf, uint32(elem.Value))
+		default:
+			buf = op.Endian.AppendUint16(buf, uint16(elem.Value))
+		}
+	}
+	return buf
+}
+
+// MarshalBinary encodes the whole state of the game.
+// The observer is not part of the snapshot.
+func (cw *Corewar) MarshalBinary() ([]byte, error) {
+	playerIndex := func(p *Player) int {
+		for i, elem := range cw.Players {
+			if elem == p {
+				return i
+			}
+		}
+		return -1
+	}
+
+	w := &snapshotWriter{buf: make([]byte, 0, 2*len(cw.Ram))}
+	w.buf = append(w.buf, snapshotMagic...)
+	w.int(snapshotVersion)
+
+	// Config.
+	cfg := cw.Config
+	w.int(cfg.MemSize)
+	w.int(cfg.IdxMod)
+	w.int(cfg.CyclesToDie)
+	w.int(cfg.CycleDelta)
+	w.int(cfg.NumLives)
+	w.int(cfg.MaxChecks)
+	w.bool(cfg.TrackOwnership)
+	w.int(len(cfg.Players))
+	for _, elem := range cfg.Players {
+		w.int(elem.Number)
+		w.bytes(elem.Data)
+	}
+
+	// Memory.
+	w.bytes(cw.Ram)
+	w.bool(cw.Owners != nil)
+	for _, elem := range cw.Owners {
+		w.int(elem.Player)
+		w.int(int(elem.Access))
+	}
+
+	// Players.
+	w.int(len(cw.Players))
+	for _, p := range cw.Players {
+		w.string(p.Name)
+		w.int(p.Number)
+		w.bool(p.Dead)
+		w.int(p.ProcessCount)
+		w.int(p.TotalLives)
+		w.int(p.CurrentLives)
+		w.int(p.LastLiveCycle)
+		w.int(p.LastLivePID)
+	}
+
+	// Processes.
+	w.int(len(cw.Processes))
+	for _, p := range cw.Processes {
+		idx := playerIndex(p.Player)
+		if idx < 0 {
+			return nil, fmt.Errorf("process %d: unknown player", p.ID)
+		}
+		w.int(p.ID)
+		w.int(idx)
+		for _, r := range p.Registers {
+			w.uint(uint64(r))
+		}
+		w.uint(uint64(p.PC))
+		w.bool(p.Carry)
+		w.int(p.WaitCycles)
+		w.int(p.LastLive)
+		w.bool(p.CurInstruction != nil)
+		if p.CurInstruction != nil {
+			w.bytes(encodeInstruction(p.CurInstruction))
+		}
+	}
+
+	// Counters.
+	w.int(cw.NextPID)
+	w.int(cw.Cycle)
+	w.int(cw.CyclesToDie)
+	w.int(cw.CurCyclesToDie)
+	w.int(cw.LiveCalls)
+	w.int(cw.Checks)
+	w.int(playerIndex(cw.LastAlive))
+	w.int(playerIndex(cw.Winner))
+	w.int(int(cw.EndReason))
+
+	return w.buf, nil
+}
+
+// UnmarshalBinary restores a game encoded with MarshalBinary,
+// replacing the whole state. The observer and history settings already set
+// in the config are kept, the history restarts from the restored state.
+// The breakpoints are kept as well. If the ownership is tracked but the
+// snapshot was saved without it, it starts over empty.
+func (cw *Corewar) UnmarshalBinary(data []byte) error {
+	if len(data) < len(snapshotMagic) || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return fmt.Errorf("%w: bad magic", ErrInvalidSnapshot)
+	}
+	r := &snapshotReader{buf: data[len(snapshotMagic):]}
+	if v := r.int(); r.err == nil && v != snapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d, expect %d", ErrInvalidSnapshot, v, snapshotVersion)
+	}
+
+	// Config.
+	cfg := Config{Observer: cw.Config.Observer, History: cw.Config.History}
+	cfg.MemSize = r.int()
+	cfg.IdxMod = r.int()
+	cfg.CyclesToDie = r.int()
+	cfg.CycleDelta = r.int()
+	cfg.NumLives = r.int()
+	cfg.MaxChecks = r.int()
+	cfg.TrackOwnership = r.bool()
+	cfg.Players = make([]PlayerConfig, r.count(len(r.buf)))
+	for i := range cfg.Players {
+		cfg.Players[i] = PlayerConfig{Number: r.int(), Data: r.bytes()}
+	}
+
+	// Memory.
+	ram := Ram(r.bytes())
+	if r.err == nil && (len(ram) == 0 || len(ram) != cfg.MemSize) {
+		r.fail("memory size %d, expect %d", len(ram), cfg.MemSize)
+	}
+	var owners Ownership
+	if r.bool() {
+		owners = make(Ownership, len(ram))
+		for i := range owners {
+			owners[i] = Cell{Player: r.int(), Access: AccessType(r.int())}
+		}
+	}
+
+	if owners == nil && cw.Config.TrackOwnership && r.err == nil {
+		owners = make(Ownership, len(ram))
+		cfg.TrackOwnership = true
+	}
+
+	// Players.
+	players := make([]*Player, r.count(len(r.buf)))
+	for i := range players {
+		players[i] = &Player{
+			Name:          r.string(),
+			Number:        r.int(),
+			Dead:          r.bool(),
+			ProcessCount:  r.int(),
+			TotalLives:    r.int(),
+			CurrentLives:  r.int(),
+			LastLiveCycle: r.int(),
+			LastLivePID:   r.int(),
+		}
+	}
+	player := func() *Player {
+		idx := r.int()
+		if idx == -1 {
+			return nil
+		}
+		if idx < 0 || idx >= len(players) {
+			r.fail("invalid player index %d", idx)
+			return nil
+		}
+		return players[idx]
+	}
+
+	// Processes.
+	processes := make([]*Process, r.count(len(r.buf)))
+	for i := range processes {
+		p := &Process{ID: r.int(), Player: player()}
+		if r.err == nil && p.Player == nil {
+			r.fail("process %d without player", p.ID)
+		}
+		for j := range p.Registers {
+			p.Registers[j] = uint32(r.uint())
+		}
+		p.PC = uint32(r.uint())
+		if r.err == nil && int(p.PC) >= len(ram) {
+			r.fail("process %d PC %d out of memory", p.ID, p.PC)
+		}
+		p.Carry = r.bool()
+		p.WaitCycles = r.int()
+		p.LastLive = r.int()
+		if r.bool() {
+			var buf [maxInstructionSize]byte
+			copy(buf[:], r.bytes())
+			ins, _, err := parser.DecodeNextInstruction(buf[:])
+			if err != nil && r.err == nil {
+				r.fail("process %d instruction: %s", p.ID, err)
+			}
+			p.CurInstruction = ins
+		}
+		processes[i] = p
+	}
+
+	// Counters.
+	next := Corewar{
+		Config:    cfg,
+		Ram:       ram,
+		Owners:    owners,
+		Players:   players,
+		Processes: processes,
+
+		NextPID:        r.int(),
+		Cycle:          r.int(),
+		CyclesToDie:    r.int(),
+		CurCyclesToDie: r.int(),
+		LiveCalls:      r.int(),
+		Checks:         r.int(),
+		LastAlive:      player(),
+		Winner:         player(),
+		EndReason:      EndReason(r.int()),
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if len(r.buf) != 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrInvalidSnapshot, len(r.buf))
+	}
+
+	if cfg.History > 0 {
+		next.history = &history{interval: cfg.History}
+	}
+	next.breakpoints, next.nextBreakID = cw.breakpoints, cw.nextBreakID
+	*cw = next
+	if cfg.Observer != nil {
+		// Let the observer know the whole memory changed with the next round.
+		cw.changes = append(cw.changes, MemChange{Addr: 0, Size: len(ram), Access: AccessNone})
+	}
+	return nil
+}
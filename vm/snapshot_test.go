@@ -0,0 +1,114 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	tests := []struct {
+		name      string
+		cycle     int
+		ownership bool
+	}{
+		{name: "start", cycle: 0},
+		{name: "running", cycle: 1234},
+		{name: "after a death", cycle: 4000},
+		{name: "ownership", cycle: 1234, ownership: true},
+		{name: "game over", cycle: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t, forker, bomber, quitter)
+			cfg.TrackOwnership = tt.ownership
+			cw := newTestCorewar(t, cfg)
+			if err := cw.RunUntil(context.Background(), tt.cycle); err != nil && !errors.Is(err, io.EOF) {
+				t.Fatalf("Failed to run until cycle %d: %s.", tt.cycle, err)
+			}
+			data, err := cw.MarshalBinary()
+			if err != nil {
+				t.Fatalf("Failed to marshal: %s.", err)
+			}
+
+			restored := &Corewar{}
+			if err := restored.UnmarshalBinary(data); err != nil {
+				t.Fatalf("Failed to unmarshal: %s.", err)
+			}
+			data2, err := restored.MarshalBinary()
+			if err != nil {
+				t.Fatalf("Failed to marshal the restored game: %s.", err)
+			}
+			if !bytes.Equal(data, data2) {
+				t.Fatal("The restored game doesn't marshal to the same snapshot.")
+			}
+			if tt.ownership && !slices.Equal(cw.Owners, restored.Owners) {
+				t.Fatal("The ownership is not restored.")
+			}
+
+			// Both games go on the same way.
+			for _, game := range []*Corewar{cw, restored} {
+				if err := game.RunUntil(context.Background(), game.Cycle+1000); err != nil && !errors.Is(err, io.EOF) {
+					t.Fatalf("Failed to run: %s.", err)
+				}
+			}
+			data, err = cw.MarshalBinary()
+			if err != nil {
+				t.Fatalf("Failed to marshal: %s.", err)
+			}
+			data2, err = restored.MarshalBinary()
+			if err != nil {
+				t.Fatalf("Failed to marshal the restored game: %s.", err)
+			}
+			if !bytes.Equal(data, data2) {
+				t.Fatal("The restored game went on differently.")
+			}
+		})
+	}
+}
+
+func TestSnapshotTrackOwnership(t *testing.T) {
+	cw := newTestCorewar(t, testConfig(t, forker, bomber))
+	data, err := cw.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to marshal: %s.", err)
+	}
+	restored := &Corewar{Config: Config{TrackOwnership: true}}
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Failed to unmarshal: %s.", err)
+	}
+	if len(restored.Owners) != len(restored.Ram) {
+		t.Fatalf("Ownership of %d cells, expected %d.", len(restored.Owners), len(restored.Ram))
+	}
+	if err := restored.RunUntil(context.Background(), 100); err != nil {
+		t.Fatalf("Failed to run with the ownership: %s.", err)
+	}
+}
+
+func TestSnapshotInvalid(t *testing.T) {
+	cw := newTestCorewar(t, testConfig(t, forker, bomber))
+	data, err := cw.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to marshal: %s.", err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty"},
+		{name: "bad magic", data: append([]byte("XXXX"), data[4:]...)},
+		{name: "bad version", data: append([]byte(snapshotMagic+"\x7f"), data[5:]...)},
+		{name: "truncated", data: data[:len(data)/2]},
+		{name: "trailing data", data: append(bytes.Clone(data), 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (&Corewar{}).UnmarshalBinary(tt.data); !errors.Is(err, ErrInvalidSnapshot) {
+				t.Fatalf("Unexpected error: %v, expected %s.", err, ErrInvalidSnapshot)
+			}
+		})
+	}
+}
@@ -3,12 +3,9 @@ package vm
 
 import (
 	_ "embed"
-	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"slices"
-	"strings"
 
 	"go.creack.net/corewar/asm/parser"
 	"go.creack.net/corewar/op"
@@ -23,6 +20,7 @@ type Process struct {
 	Carry          bool
 	CurInstruction *parser.Instruction
 	WaitCycles     int
+	LastLive       int // Cycle of the last 'live' executed by the process.
 }
 
 type Player struct {
@@ -34,6 +32,9 @@ type Player struct {
 
 	TotalLives   int // Total number of 'live' calls.'
 	CurrentLives int // Number of 'live' calls in the current CyclesToDie window.
+
+	LastLiveCycle int // Cycle of the last valid 'live' call for the player.
+	LastLivePID   int // ID of the process which made the last valid 'live' call, 0 if none.
 }
 
 type PlayerConfig struct {
@@ -44,36 +45,67 @@ type PlayerConfig struct {
 type Config struct {
 	MemSize     int // Size of the memory.
 	IdxMod      int // Index modulo, i.e. how far can a player go in the memory (except for long instructions).
-	CyclesToDie int // Window where players need to say they are alive.
+	CyclesToDie int // Initial window where players need to say they are alive.
 	CycleDelta  int // How many cycles to remove from CyclesToDie NumLives is reached.
-	NumLives    int // Number of 'live' calls before updating CyclesToDie.
+	NumLives    int // Number of 'live' calls in a window before updating CyclesToDie.
+	MaxChecks   int // Number of checks without reaching NumLives before updating CyclesToDie anyway. 0 to disable.
+
+	TrackOwnership bool // Keep track of which player last used each memory cell.
+	History        int  // Cycles between checkpoints to go back in time, 0 to disable. Costs memory for each round in between.
 
 	Players []PlayerConfig
+
+	// Observer receives the VM events. Can be nil.
+	Observer Observer `json:"-"`
 }
 
+// NOTE: The longest instruction is 20 bytes. 4 bytes for instructions, 4 bytes each params, 4 params max.
+const maxInstructionSize = 4 + 4*op.MaxArgsNumber
+
 type Corewar struct {
 	Config Config
 
-	Ram Ram
+	Ram    Ram
+	Owners Ownership // Who last used each memory cell, nil unless Config.TrackOwnership is set.
 
 	Players   []*Player
 	Processes []*Process
 	NextPID   int
 
 	Cycle          int // Current cycle.
-	CurCyclesToDie int // How many cycles until death players have after each 'live' call.
+	CyclesToDie    int // Current window length, decreases over time.
+	CurCyclesToDie int // How many cycles until the next check.
 	LiveCalls      int // Number of 'live' calls since last check.
+	Checks         int // Number of consecutive checks without decreasing CyclesToDie.
+
+	LastAlive *Player   // Last player reported alive by a valid 'live' call.
+	Winner    *Player   // Set when the game ends.
+	EndReason EndReason // Why the game ended, EndNone while running.
+
+	changes   []MemChange // Memory changes of the current round, only tracked with an observer.
+	stopCycle int         // Cycle NextCycle must not skip, set by RunUntil.
+	exec      ExecEvent   // Current instruction details, filled by the ops.
+	history   *history    // Nil unless Config.History is set.
+	decodeBuf [maxInstructionSize]byte
 
-	// Messages is a channel where the VM will send messages.
-	// Needs to be consumed otherwise it will block.
-	Messages chan Message `json:"-"`
+	breakpoints []Breakpoint
+	nextBreakID int
+	hits        []BreakHit // Breakpoints triggered during the current round.
 }
 
 // NextCycle advances the cycle counter until a player is ready to go
-// or cycleToDie expires.
+// or cycleToDie expires, without going past the cycle given to RunUntil
+// nor the cycle breakpoints. After a breakpoint hit, it only advances
+// one cycle so the game stops right after the cycle of the hit.
 // Useful when everyone is waiting for a long instruction like fork.
 func (cw *Corewar) NextCycle() {
 	cycles := cw.CurCyclesToDie
+	if cw.stopCycle > cw.Cycle {
+		cycles = min(cycles, cw.stopCycle-cw.Cycle)
+	}
+	if next := cw.nextBreakCycle(); next > 0 {
+		cycles = min(cycles, next-cw.Cycle)
+	}
 
 	// Then check in how many cycles the next process
 	// instruction is ready to execute.
@@ -83,12 +115,18 @@ func (cw *Corewar) NextCycle() {
 		}
 	}
 
-	// Make sure we always advance at least one cycle.
-	if cycles <= 1 {
+	// Make sure we always advance at least one cycle,
+	// and only one after a hit.
+	if cycles <= 1 || len(cw.hits) > 0 {
 		cycles = 1
 	}
 
 	// Update the cycle count.
+	if o := cw.Config.Observer; o != nil {
+		for c := cw.Cycle + 1; c <= cw.Cycle+cycles; c++ {
+			o.OnCycle(CycleEvent{Cycle: c})
+		}
+	}
 	cw.Cycle += cycles
 	cw.CurCyclesToDie -= cycles
 
@@ -100,18 +138,6 @@ func (cw *Corewar) NextCycle() {
 	}
 }
 
-// func (cw *Corewar) GetRamValue32(addr uint32) uint32 {
-// 	return op.Endian.Uint32(cw.Ram[addr%uint32(len(cw.Ram)):])
-// }
-
-// func (cw *Corewar) GetRamValueIndex(pc uint32, idx int16, mod int64) uint16 {
-// 	return op.Endian.Uint16(cw.Ram[(int64(pc)+(int64(idx)%mod))%int64(len(cw.Ram)):])
-// }
-
-// func (cw *Corewar) SetRamValue(addr uint32, value uint32) {
-// 	op.Endian.PutUint32(cw.Ram[addr%uint32(len(cw.Ram)):], value)
-// }
-
 func opAdd(a, b int64) int64 { return a + b }
 func opSub(a, b int64) int64 { return a - b }
 func opAnd(a, b int64) int64 { return a & b }
@@ -131,7 +157,7 @@ func mathOp(operation func(a, b int64) int64) func(cw *Corewar, p *Process) bool
 		} else if ins.Params[0].Typ == op.TDir {
 			source1 = int64(ins.Params[0].Value)
 		} else {
-			source1 = int64(cw.Ram.GetRamValue32(p, uint32(int64(p.PC)+int64(ins.Params[0].Value)%int64(cw.Config.IdxMod))))
+			source1 = int64(cw.readRam32(p, uint32(int64(p.PC)+int64(ins.Params[0].Value)%int64(cw.Config.IdxMod))))
 		}
 
 		if ins.Params[1].Typ == op.TReg {
@@ -139,10 +165,11 @@ func mathOp(operation func(a, b int64) int64) func(cw *Corewar, p *Process) bool
 		} else if ins.Params[1].Typ == op.TDir {
 			source2 = int64(ins.Params[1].Value)
 		} else {
-			source2 = int64(cw.Ram.GetRamValue32(p, uint32(int64(p.PC)+int64(ins.Params[1].Value)%int64(cw.Config.IdxMod))))
+			source2 = int64(cw.readRam32(p, uint32(int64(p.PC)+int64(ins.Params[1].Value)%int64(cw.Config.IdxMod))))
 		}
 
 		p.Registers[target] = uint32(operation(source1, source2))
+		cw.exec.Values = [op.MaxArgsNumber]int64{source1, source2, ins.Params[2].Value}
 
 		p.Carry = p.Registers[target] == 0
 		return true
@@ -161,15 +188,24 @@ var ops = func() map[int]func(cw *Corewar, p *Process) bool {
 		ins := p.CurInstruction
 
 		cw.LiveCalls++ // Global live count increases event if the target player is invalid/dead.
+		p.LastLive = cw.Cycle
+		cw.exec.Values[0] = int64(int32(ins.Params[0].Value))
 		i := slices.IndexFunc(cw.Players, func(p *Player) bool { return p.Number == int(ins.Params[0].Value) })
 		if i == -1 || i >= len(cw.Players) || cw.Players[i].Dead {
-			cw.Messages <- NewMessage(MsgLiveMiss, p, fmt.Sprintf("Missed 'live' from %d (%s)", p.Player.Number, p.Player.Name))
+			if o := cw.Config.Observer; o != nil {
+				o.OnLive(LiveEvent{Process: p, Target: int(ins.Params[0].Value)})
+			}
 			return true
 		}
 		targetPlayer := cw.Players[i]
 		targetPlayer.TotalLives++
 		targetPlayer.CurrentLives++
-		cw.Messages <- NewMessage(MsgLive, p, fmt.Sprintf("Player %d (%s) is alive", targetPlayer.Number, targetPlayer.Name))
+		targetPlayer.LastLiveCycle = cw.Cycle
+		targetPlayer.LastLivePID = p.ID
+		cw.LastAlive = targetPlayer
+		if o := cw.Config.Observer; o != nil {
+			o.OnLive(LiveEvent{Process: p, Target: int(ins.Params[0].Value), Player: targetPlayer})
+		}
 		return true
 	}
 
@@ -193,15 +229,15 @@ var ops = func() map[int]func(cw *Corewar, p *Process) bool {
 		// If the first param is a Direct value, use it directly.
 		if ins.Params[0].Typ == op.TDir {
 			p.Registers[r] = uint32(ins.Params[0].Value)
-			cw.Messages <- NewMessage(MsgDebug, p, fmt.Sprintf("LD Direct 0x%04x into R%d", ins.Params[0].Value, r))
 		} else {
 			// If the first param is an indirect value, we need to
 			// read the value from the RAM.
 			// - `ld 34,r3` loads the REG_SIZE bytes starting at the address PC + 34 % IDX_MOD into r3.
-			p.Registers[r] = cw.Ram.GetRamValue32(p, uint32(int64(p.PC)+int64(ins.Params[0].Value)%mod))
-			cw.Messages <- NewMessage(MsgDebug, p, fmt.Sprintf("LD RAM %d (0x%04x) into R%d", uint32(int64(ins.Params[0].Value)%mod), p.Registers[r], r))
+			p.Registers[r] = cw.readRam32(p, uint32(int64(p.PC)+int64(ins.Params[0].Value)%mod))
 		}
 
+		cw.exec.Values = [op.MaxArgsNumber]int64{int64(int32(p.Registers[r])), ins.Params[1].Value}
+
 		// Update the carry.
 		p.Carry = p.Registers[r] == 0
 
@@ -217,28 +253,22 @@ var ops = func() map[int]func(cw *Corewar, p *Process) bool {
 
 		// Source: register content.
 		source := p.Registers[ins.Params[0].Value-1]
+		cw.exec.Values = [op.MaxArgsNumber]int64{int64(int32(source)), ins.Params[1].Value}
+		if ins.Params[1].Typ != op.TReg {
+			cw.exec.Values[1] = int64(int16(ins.Params[1].Value))
+		}
 
 		// If the target is a register, we replace its value.
 		// - `st r2,r8` copies the content of r3 into r8.
 		if ins.Params[1].Typ == op.TReg {
 			p.Registers[ins.Params[1].Value-1] = source
-			if ins.Params[0].Typ == op.TReg {
-				cw.Messages <- NewMessage(MsgDebug, p, fmt.Sprintf("ST R%d (0x%04x) into R%d", ins.Params[0].Value, source, ins.Params[1].Value-1))
-			} else {
-				cw.Messages <- NewMessage(MsgDebug, p, fmt.Sprintf("ST RAM %d (0x%04x) into R%d", ins.Params[0].Value, source, ins.Params[1].Value-1))
-			}
 			return true
 		}
 
 		// If the target is an indirect value, we store the content of the
 		// source register into the RAM.
 		// - `st r4,34` stores the content of r4 at the address PC + 34 % IDX_MOD.
-		cw.Ram.SetRamValue(p, uint32(int64(p.PC)+ins.Params[1].Value%int64(cw.Config.IdxMod)), source)
-		if ins.Params[0].Typ == op.TReg {
-			cw.Messages <- NewMessage(MsgDebug, p, fmt.Sprintf("ST R%d (0x%04x) into RAM %d", ins.Params[0].Value, source, ins.Params[1].Value%int64(cw.Config.IdxMod)))
-		} else {
-			cw.Messages <- NewMessage(MsgDebug, p, fmt.Sprintf("ST RAM %d (0x%04x) into RAM %d", ins.Params[0].Value, source, ins.Params[1].Value%int64(cw.Config.IdxMod)))
-		}
+		cw.writeRam(p, uint32(int64(p.PC)+ins.Params[1].Value%int64(cw.Config.IdxMod)), source)
 		return true
 	}
 
@@ -262,13 +292,16 @@ var ops = func() map[int]func(cw *Corewar, p *Process) bool {
 	// is set to 1.
 	// 1 Param: Always a direct value as index (int16).
 	ops[0x09] = func(cw *Corewar, p *Process) bool {
-		if !p.Carry {
-			return true // Advance the PC.
-		}
 		ins := p.CurInstruction
 
 		// `zjmp %23` puts, if carry equals 1, PC + 23 % IDX_MOD into the PC.
 		newPC := int32(p.PC) + (int32(int16(ins.Params[0].Value)) % int32(cw.Config.IdxMod))
+		cw.exec.Values[0] = int64(int16(ins.Params[0].Value))
+		cw.exec.Target = int64(newPC)
+		if !p.Carry {
+			return true // Advance the PC.
+		}
+		cw.exec.Jumped = true
 		if newPC < 0 {
 			newPC += int32(len(cw.Ram))
 		}
@@ -305,8 +338,7 @@ var ops = func() map[int]func(cw *Corewar, p *Process) bool {
 			source1 = int16(ins.Params[0].Value)
 		} else {
 			// If indirect, read int16 (2) bytes from RAM at PC + <val> % IDX_MOD.
-			cw.Messages <- NewMessage(MsgPause, nil, "")
-			source1 = int16(cw.Ram.GetRamValue16(p, uint32(int32(p.PC)+(int32(int16(ins.Params[0].Value))%mod))))
+			source1 = int16(cw.readRam16(p, uint32(int32(p.PC)+(int32(int16(ins.Params[0].Value))%mod))))
 		}
 		if ins.Params[1].Typ == op.TReg {
 			source2 = int16(p.Registers[ins.Params[1].Value-1])
@@ -318,7 +350,10 @@ var ops = func() map[int]func(cw *Corewar, p *Process) bool {
 		// The sum is named S.
 		// REG_SIZE bytes are read from the address PC + S % IDX_MOD and copied into r1.
 		S := source1 + source2
-		p.Registers[target] = cw.Ram.GetRamValue32(p, uint32(int32(p.PC)+int32(S)%mod))
+		p.Registers[target] = cw.readRam32(p, uint32(int32(p.PC)+int32(S)%mod))
+		cw.exec.Values = [op.MaxArgsNumber]int64{int64(source1), int64(source2), ins.Params[2].Value}
+		cw.exec.Offset = int64(S)
+		cw.exec.Target = int64(int32(p.PC) + int32(S)%mod)
 
 		return true
 	}
@@ -345,7 +380,7 @@ var ops = func() map[int]func(cw *Corewar, p *Process) bool {
 			target1 = int16(ins.Params[1].Value)
 		} else {
 			// If indirect, read int16 (2) bytes from RAM at PC + <val> % IDX_MOD.
-			target1 = int16(cw.Ram.GetRamValue16(p, uint32(int32(p.PC)+(int32(int16(ins.Params[1].Value))%int32(cw.Config.IdxMod)))))
+			target1 = int16(cw.readRam16(p, uint32(int32(p.PC)+(int32(int16(ins.Params[1].Value))%int32(cw.Config.IdxMod)))))
 		}
 		if ins.Params[2].Typ == op.TReg {
 			target2 = int16(p.Registers[ins.Params[2].Value-1])
@@ -353,10 +388,12 @@ var ops = func() map[int]func(cw *Corewar, p *Process) bool {
 			target2 = int16(ins.Params[2].Value)
 		}
 
-		cw.Messages <- NewMessage(MsgDebug, p, fmt.Sprintf("STI R%d %s", ins.Params[0].Value, ins))
 		// `sti r2,%4,%5` copies the content of r2 into the address PC + (4+5) % IDX_MOD.
 		S := target1 + target2
-		cw.Ram.SetRamValue(p, uint32(int32(p.PC)+int32(S)%int32(cw.Config.IdxMod)), source)
+		cw.writeRam(p, uint32(int32(p.PC)+int32(S)%int32(cw.Config.IdxMod)), source)
+		cw.exec.Values = [op.MaxArgsNumber]int64{int64(int32(source)), int64(target1), int64(target2)}
+		cw.exec.Offset = int64(S)
+		cw.exec.Target = int64(int32(p.PC) + int32(S)%int32(cw.Config.IdxMod))
 
 		return true
 	}
@@ -371,14 +408,21 @@ var ops = func() map[int]func(cw *Corewar, p *Process) bool {
 		if ins.OpCode.Code == 0x0f { // lfork is the same s fork but without modulo.
 			mod = 1
 		}
+		cw.exec.Values[0] = int64(int16(ins.Params[0].Value))
+		cw.exec.Target = int64(p.PC) + int64(int16(ins.Params[0].Value))%mod
 		newProcess := *p
 		newProcess.CurInstruction = nil
 		newProcess.PC = uint32((int64(p.PC) + (int64(int16(ins.Params[0].Value)) % mod)) % int64(len(cw.Ram)))
 		newProcess.ID = cw.NextPID
 		cw.NextPID++
-		cw.Messages <- NewMessage(MsgDebug, p, fmt.Sprintf("Forking process %d to %d", p.ID, newProcess.ID))
 		cw.Processes = append(cw.Processes, &newProcess)
 		p.Player.ProcessCount++
+		if len(cw.breakpoints) > 0 {
+			cw.checkAddr(&newProcess) // Created on the address, it doesn't move to it.
+		}
+		if o := cw.Config.Observer; o != nil {
+			o.OnFork(ForkEvent{Parent: p, Child: &newProcess, Long: ins.OpCode.Code == 0x0f})
+		}
 		return true
 	}
 
@@ -403,8 +447,11 @@ var ops = func() map[int]func(cw *Corewar, p *Process) bool {
 
 		// Target register.
 		r := ins.Params[0].Value - 1
+		cw.exec.Values[0] = int64(p.Registers[r])
 
-		cw.Messages <- NewMessage(MsgDisplay, p, fmt.Sprintf("%c", p.Registers[r]%256))
+		if o := cw.Config.Observer; o != nil {
+			o.OnDisplay(DisplayEvent{Process: p, Char: byte(p.Registers[r] % 256)})
+		}
 
 		return true
 	}
@@ -433,12 +480,24 @@ func (cw *Corewar) Exec(p *Process) bool {
 		}
 	}
 
-	cw.Messages <- NewMessage(MsgDebug, p, fmt.Sprintf("2Executing %s %v", ins, ins.Params))
 	f, ok := ops[int(ins.OpCode.Code)]
 	if !ok {
 		return true
 	}
-	return f(cw, p)
+	// Like the invalid bytes, the noop of the empty memory is not
+	// reported, see ProcessTurn.
+	if ins.OpCode.Code == 0x00 {
+		return f(cw, p)
+	}
+	cw.exec = ExecEvent{Process: p, Instruction: ins, PC: p.PC}
+	advance := f(cw, p)
+	if o := cw.Config.Observer; o != nil {
+		o.OnExec(cw.exec)
+	}
+	if len(cw.breakpoints) > 0 {
+		cw.checkOpcode(p, ins.OpCode.Code, cw.exec.PC)
+	}
+	return advance
 }
 
 // ProcessTurn executes the current process' instruction.
@@ -453,18 +512,25 @@ func (cw *Corewar) ProcessTurn(p *Process) error {
 	// If we had an instruction buffered, execute it.
 	if p.CurInstruction != nil {
 		if cw.Exec(p) {
+			if o := cw.Config.Observer; o != nil && p.CurInstruction.OpCode.Code != 0x00 {
+				buf := cw.decodeBuf[:p.CurInstruction.Size]
+				cw.Ram.Read(p.PC, buf)
+				o.OnMove(MoveEvent{Process: p, From: p.PC, Bytes: buf})
+			}
 			p.PC += uint32(p.CurInstruction.Size)
 			p.PC %= uint32(len(cw.Ram))
 		}
 	}
 
 	// Decode the next instruction.
-	// NOTE: The longest instruction is 20 bytes. 4 bytes for instructions, 4 bytes each params, 4 params max.
-	ins, _, err := parser.DecodeNextInstruction(cw.Ram.Bytes(p.PC, 4+4*op.MaxArgsNumber))
+	cw.Ram.Read(p.PC, cw.decodeBuf[:])
+	ins, _, err := parser.DecodeNextInstruction(cw.decodeBuf[:])
 	if err != nil {
-		// If the instruction is not valid, we consider it as a no-op.
+		// If the instruction is not valid, we consider it as a no-op,
+		// without anything left to execute.
 		p.PC++
 		p.PC %= uint32(len(cw.Ram))
+		p.CurInstruction = nil
 		p.WaitCycles = 1
 		return nil
 	}
@@ -475,11 +541,12 @@ func (cw *Corewar) ProcessTurn(p *Process) error {
 }
 
 func (cw *Corewar) Round() error {
-	// Check if we need to update the cycles to die.
-	if cw.LiveCalls >= cw.Config.NumLives {
-		cw.LiveCalls = 0
-		cw.Config.CyclesToDie -= cw.Config.CycleDelta
+	if cw.history != nil {
+		if err := cw.recordRound(); err != nil {
+			return fmt.Errorf("record history: %w", err)
+		}
 	}
+
 	// Check for death.
 	if cw.CurCyclesToDie == 0 {
 		// CurCyclesToDie is expired, check for players that are dead.
@@ -491,31 +558,44 @@ func (cw *Corewar) Round() error {
 				p.Dead = true
 				// NOTE: We don't reset the process count to display it.
 				// Delete the processes themselves though.
+				var killed []*Process
 				cw.Processes = slices.DeleteFunc(cw.Processes, func(process *Process) bool {
-					return process.Player.Number == p.Number
+					if process.Player.Number != p.Number {
+						return false
+					}
+					killed = append(killed, process)
+					return true
 				})
-				cw.Messages <- NewMessage(MsgDead, &Process{ID: p.Number, Player: p}, fmt.Sprintf("Player %d (%s) died", p.Number, p.Name))
+				if o := cw.Config.Observer; o != nil {
+					o.OnDeath(DeathEvent{Player: p, Processes: killed, Cycle: cw.Cycle, CyclesToDie: cw.CyclesToDie})
+				}
 				continue
 			}
 			p.CurrentLives = 0
 		}
 
 		if len(cw.Processes) <= 1 {
-			return io.EOF
+			return cw.gameOver(EndNoProcess)
+		}
+
+		// Check if we need to update the cycles to die.
+		// Either enough 'live' calls happened during the window,
+		// or we went through too many checks without it.
+		cw.Checks++
+		decreased := cw.LiveCalls >= cw.Config.NumLives || (cw.Config.MaxChecks > 0 && cw.Checks >= cw.Config.MaxChecks)
+		if decreased {
+			cw.CyclesToDie -= cw.Config.CycleDelta
+			cw.Checks = 0
+		}
+		cw.LiveCalls = 0
+		if o := cw.Config.Observer; o != nil {
+			o.OnCheck(CheckEvent{Cycle: cw.Cycle, CyclesToDie: cw.CyclesToDie, Decreased: decreased})
 		}
 
 		// Reset CurCyclesToDie.
-		cw.CurCyclesToDie = cw.Config.CyclesToDie
+		cw.CurCyclesToDie = cw.CyclesToDie
 		if cw.CurCyclesToDie <= 0 {
-			var tie []string
-			for _, elem := range cw.Players {
-				if !elem.Dead {
-					tie = append(tie, fmt.Sprintf("%d (%s)", elem.Number, elem.Name))
-				}
-			}
-			// TODO: Keep track of when was the last live called for each player as this scenario may not be a tie.
-			cw.Messages <- NewMessage(MsgGameOver, nil, fmt.Sprintf("Game over, tie %d players: %s", len(tie), strings.Join(tie, ",")))
-			return io.EOF
+			return cw.gameOver(EndCyclesToDie)
 		}
 	}
 
@@ -527,41 +607,166 @@ func (cw *Corewar) Round() error {
 		}
 	}
 	if alive <= 1 {
-		cw.Messages <- NewMessage(MsgGameOver, nil, fmt.Sprintf("Game over, %d players alive", alive))
-		return io.EOF
+		return cw.gameOver(EndLastPlayer)
 	}
 
 	for _, p := range cw.Processes {
+		pc := p.PC
+		if cw.history != nil && p.WaitCycles <= 0 {
+			cw.recordProcess(p) // Only the processes whose turn it is change.
+		}
 		if err := cw.ProcessTurn(p); err != nil {
 			return fmt.Errorf("failed to execute process %d (player %d) turn: %w", p.ID, p.Player.Number, err)
 		}
+		if p.PC != pc && len(cw.breakpoints) > 0 {
+			cw.checkAddr(p)
+		}
 	}
 	cw.NextCycle()
+	if len(cw.breakpoints) > 0 {
+		cw.checkCycle()
+	}
+
+	cw.flushChanges()
 
-	buf, _ := json.Marshal(cw.Ram)
-	cw.Messages <- NewMessage(MsgDump, nil, string(buf))
+	// Report the breakpoints once the round is complete.
+	if len(cw.hits) > 0 {
+		err := &BreakError{Hits: cw.hits}
+		cw.hits = nil
+		return err
+	}
 
 	return nil
 }
 
-func NewCorewar(cfg Config) *Corewar {
+// gameOver marks the game as ended for the given reason, declares
+// the winner and notifies the observer. Always returns io.EOF.
+//
+// The winner is the last player reported alive. If nobody
+// ever called 'live', the last player wins.
+func (cw *Corewar) gameOver(reason EndReason) error {
+	cw.EndReason = reason
+	cw.Winner = cw.LastAlive
+	if cw.Winner == nil && len(cw.Players) > 0 {
+		cw.Winner = cw.Players[len(cw.Players)-1]
+	}
+	if o := cw.Config.Observer; o != nil {
+		var alive []*Player
+		for _, elem := range cw.Players {
+			if !elem.Dead {
+				alive = append(alive, elem)
+			}
+		}
+		o.OnGameOver(GameOverEvent{Reason: reason, Winner: cw.Winner, Alive: alive})
+	}
+	return io.EOF
+}
+
+// touch records the memory access in the ownership layer
+// and in the round changes when enabled.
+func (cw *Corewar) touch(p *Process, addr uint32, size int, access AccessType) {
+	if cw.history != nil {
+		cw.recordMemory(addr, size)
+	}
+	if cw.Owners != nil {
+		cw.Owners.Set(addr, size, p.Player.Number, access)
+	}
+	if cw.Config.Observer != nil {
+		cw.changes = append(cw.changes, MemChange{Addr: addr % uint32(len(cw.Ram)), Size: size, Process: p, Access: access})
+	}
+}
+
+// writeRam stores the value in memory and notifies the observer.
+func (cw *Corewar) writeRam(p *Process, addr, value uint32) {
+	cw.touch(p, addr, 4, AccessWrite) // Before writing to keep the previous value in the history.
+	cw.Ram.Write32(addr, value)
+	if len(cw.breakpoints) > 0 {
+		cw.checkWatch(p, addr, 4)
+	}
+	if o := cw.Config.Observer; o != nil {
+		o.OnWrite(WriteEvent{Process: p, Addr: addr % uint32(len(cw.Ram)), Size: 4, Value: value})
+	}
+}
+
+// readRam32 reads 4 bytes from memory and records the access.
+func (cw *Corewar) readRam32(p *Process, addr uint32) uint32 {
+	cw.touch(p, addr, 4, AccessRead)
+	return cw.Ram.Read32(addr)
+}
+
+// readRam16 reads 2 bytes from memory and records the access.
+func (cw *Corewar) readRam16(p *Process, addr uint32) uint16 {
+	cw.touch(p, addr, 2, AccessReadIndex)
+	return cw.Ram.Read16(addr)
+}
+
+// flushChanges sends the memory changes of the round to the observer.
+func (cw *Corewar) flushChanges() {
+	if o := cw.Config.Observer; o != nil {
+		o.OnRound(RoundEvent{Cycle: cw.Cycle, Changes: cw.changes})
+	}
+	cw.changes = cw.changes[:0]
+}
+
+// NewCorewar validates the players and loads them in memory,
+// evenly spaced. Returns a *PlayerError if a player can't be loaded.
+func NewCorewar(cfg Config) (*Corewar, error) {
 	headerlen, _, _ := op.HeaderStructSize()
 
-	// Make sure the given player list is sorted by number.
-	slices.SortFunc(cfg.Players, func(a, b PlayerConfig) int { return a.Number - b.Number })
+	if len(cfg.Players) == 0 {
+		return nil, ErrNoPlayers
+	}
+
+	// Sort the players by number, in a copy to leave the caller's
+	// list alone, and keep their index in it for the errors.
+	order := make([]int, len(cfg.Players))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return cfg.Players[a].Number - cfg.Players[b].Number })
+	sorted := make([]PlayerConfig, 0, len(order))
+	for _, idx := range order {
+		sorted = append(sorted, cfg.Players[idx])
+	}
+	cfg.Players = sorted
+
+	// Validate all the players before loading anything.
+	spacing := cfg.MemSize / len(cfg.Players)
+	names := make([]string, len(cfg.Players))
+	for i, pCfg := range cfg.Players {
+		if i > 0 && cfg.Players[i-1].Number == pCfg.Number {
+			return nil, &PlayerError{Number: pCfg.Number, Index: order[i], Err: ErrDuplicateNumber}
+		}
+		p, err := (&parser.Program{}).Decode(pCfg.Data, parser.WarningOptions{})
+		if err != nil {
+			return nil, &PlayerError{Number: pCfg.Number, Index: order[i], Err: fmt.Errorf("%w: %w", ErrInvalidProgram, err)}
+		}
+		names[i] = p.GetDirective(op.NameCmdString)
+		size := len(pCfg.Data) - headerlen
+		if size > op.ChampMaxSize {
+			return nil, &PlayerError{Number: pCfg.Number, Index: order[i], Err: fmt.Errorf("%w: %d bytes, maximum is %d", ErrChampionTooLarge, size, op.ChampMaxSize)}
+		}
+		if size > cfg.MemSize {
+			return nil, &PlayerError{Number: pCfg.Number, Index: order[i], Err: fmt.Errorf("%w: %d bytes, memory is %d", ErrProgramTooLarge, size, cfg.MemSize)}
+		}
+		if size > spacing && len(cfg.Players) > 1 {
+			next := cfg.Players[(i+1)%len(cfg.Players)]
+			return nil, &PlayerError{Number: pCfg.Number, Index: order[i], Err: fmt.Errorf("%w: %d bytes at %d reach player %d at %d", ErrOverlap, size, spacing*i, next.Number, spacing*((i+1)%len(cfg.Players)))}
+		}
+	}
 
 	players := make([]*Player, 0, len(cfg.Players))
 	processes := make([]*Process, 0, len(cfg.Players))
 	ram := make(Ram, cfg.MemSize)
+	var owners Ownership
+	if cfg.TrackOwnership {
+		owners = make(Ownership, cfg.MemSize)
+	}
+	var changes []MemChange
 	nextPID := 1
 	for i, pCfg := range cfg.Players {
-		p, err := (&parser.Program{}).Decode(pCfg.Data, false)
-		if err != nil {
-			log.Fatalf("failed to decode player %d: %s", i, err)
-		}
-
 		player := &Player{
-			Name:         p.GetDirective(op.NameCmdString),
+			Name:         names[i],
 			Number:       pCfg.Number,
 			ProcessCount: 1,
 		}
@@ -569,16 +774,17 @@ func NewCorewar(cfg Config) *Corewar {
 		process := &Process{
 			ID:     nextPID,
 			Player: player,
-			PC:     uint32((cfg.MemSize / len(cfg.Players)) * i),
+			PC:     uint32(spacing * i),
 		}
 		nextPID++
 		process.Registers[0] = uint32(player.Number) // R1 gets intialized to the player number.
 		processes = append(processes, process)
-		for i, elem := range pCfg.Data[headerlen:] {
-			ram[process.PC+uint32(i)%uint32(len(ram))] = RamEntry{
-				Value:   elem,
-				Process: process,
-			}
+		ram.Write(process.PC, pCfg.Data[headerlen:])
+		if owners != nil {
+			owners.Set(process.PC, len(pCfg.Data)-headerlen, player.Number, AccessNone)
+		}
+		if cfg.Observer != nil {
+			changes = append(changes, MemChange{Addr: process.PC, Size: len(pCfg.Data) - headerlen, Process: process, Access: AccessNone})
 		}
 	}
 
@@ -586,19 +792,38 @@ func NewCorewar(cfg Config) *Corewar {
 		Config: cfg,
 
 		Ram:       ram,
+		Owners:    owners,
 		Players:   players,
 		Processes: processes,
 		NextPID:   nextPID,
 
 		Cycle:          0,
+		CyclesToDie:    cfg.CyclesToDie,
 		CurCyclesToDie: cfg.CyclesToDie,
 
-		Messages: make(chan Message, 10), // Arbitrary size.
+		changes: changes, // Sent with the first round.
+	}
+	if cfg.History > 0 {
+		cw.history = &history{interval: cfg.History}
 	}
 
-	buf, _ := json.Marshal(cw.Ram)
-
-	cw.Messages <- NewMessage(MsgDump, nil, string(buf))
+	return cw, nil
+}
 
-	return cw
+// LoadOffset returns the offset of the process PC within the code
+// its player got loaded with, to map it back to the source.
+// Returns false when the PC is outside of it.
+func (cw *Corewar) LoadOffset(p *Process) (int, bool) {
+	i := slices.Index(cw.Players, p.Player)
+	if i < 0 || i >= len(cw.Config.Players) || len(cw.Ram) == 0 {
+		return 0, false
+	}
+	headerlen, _, _ := op.HeaderStructSize()
+	start := uint32(len(cw.Ram) / len(cw.Config.Players) * i)
+	size := len(cw.Config.Players[i].Data) - headerlen
+	offset := int((p.PC + uint32(len(cw.Ram)) - start) % uint32(len(cw.Ram)))
+	if offset >= size {
+		return 0, false
+	}
+	return offset, true
 }
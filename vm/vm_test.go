@@ -0,0 +1,265 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"go.creack.net/corewar/asm"
+	"go.creack.net/corewar/asm/parser"
+	"go.creack.net/corewar/op"
+)
+
+// Test champions: one forking a few writers, one bombing forward,
+// and one living once, so the games have forks, writes and deaths.
+const (
+	forker = `.name "forker"
+.comment "forks a few writers"
+
+	sti	r1, %:first, %1
+	sti	r1, %:live, %1
+	ld	%0, r2
+first:	live	%1
+	fork	%:live
+	fork	%:live
+live:	live	%1
+	st	r1, -40
+	zjmp	%:live
+`
+	bomber = `.name "bomber"
+.comment "bombs forward"
+
+	sti	r1, %:live, %1
+	ld	%4, r4
+	ld	%0, r3
+live:	live	%1
+	sti	r1, r3, %200
+	add	r3, r4, r3
+	ld	%0, r2
+	zjmp	%:live
+`
+	quitter = `.name "quitter"
+.comment "lives once"
+
+	sti	r1, %:live, %1
+	ld	%0, r2
+live:	live	%1
+wait:	zjmp	%:wait
+`
+)
+
+// compile assembles the given champion source.
+func compile(t *testing.T, src string) []byte {
+	t.Helper()
+	buf, _, err := asm.Compile("test.s", src, parser.WarningOptions{})
+	if err != nil {
+		t.Fatalf("Failed to compile: %s.", err)
+	}
+	return buf
+}
+
+// testConfig returns the default config with the given champions, numbered in order.
+func testConfig(t *testing.T, srcs ...string) Config {
+	t.Helper()
+	cfg := Config{
+		MemSize:     op.MemSize,
+		IdxMod:      op.IdxMod,
+		CyclesToDie: op.CyclesToDie,
+		CycleDelta:  op.CycleDelta,
+		NumLives:    op.NumLives,
+		MaxChecks:   op.MaxChecks,
+	}
+	for i, src := range srcs {
+		cfg.Players = append(cfg.Players, PlayerConfig{Number: i + 1, Data: compile(t, src)})
+	}
+	return cfg
+}
+
+// newTestCorewar creates a game with the given config.
+func newTestCorewar(t *testing.T, cfg Config) *Corewar {
+	t.Helper()
+	cw, err := NewCorewar(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create the game: %s.", err)
+	}
+	return cw
+}
+
+// hashAt plays a new game with the given config up to the cycle and returns its state hash.
+func hashAt(t *testing.T, cfg Config, cycle int) string {
+	t.Helper()
+	cw := newTestCorewar(t, cfg)
+	if err := cw.RunUntil(context.Background(), cycle); err != nil && !errors.Is(err, io.EOF) {
+		t.Fatalf("Failed to run until cycle %d: %s.", cycle, err)
+	}
+	return stateHash(t, cw)
+}
+
+func stateHash(t *testing.T, cw *Corewar) string {
+	t.Helper()
+	h, err := cw.StateHash()
+	if err != nil {
+		t.Fatalf("Failed to hash the state: %s.", err)
+	}
+	return h
+}
+
+func TestLoadOffset(t *testing.T) {
+	cw := newTestCorewar(t, testConfig(t, forker, quitter))
+	if err := cw.RunUntil(context.Background(), 100); err != nil {
+		t.Fatalf("Failed to run: %s.", err)
+	}
+	for _, p := range cw.Processes {
+		start := uint32(p.Player.Number-1) * 2048
+		offset, ok := cw.LoadOffset(p)
+		if !ok || offset != int(p.PC-start) {
+			t.Fatalf("Offset of process %d at 0x%04x: %d (%t), expected %d.", p.ID, p.PC, offset, ok, p.PC-start)
+		}
+	}
+
+	// Outside of the loaded code.
+	p := *cw.Processes[0]
+	p.PC = 1000
+	if offset, ok := cw.LoadOffset(&p); ok {
+		t.Fatalf("Found offset %d outside of the code.", offset)
+	}
+}
+
+// checkObserver records the checks.
+type checkObserver struct {
+	NopObserver
+	checks []CheckEvent
+}
+
+func (o *checkObserver) OnCheck(e CheckEvent) { o.checks = append(o.checks, e) }
+
+func TestMaxChecks(t *testing.T) {
+	tests := []struct {
+		name      string
+		numLives  int
+		maxChecks int
+		want      []int // CyclesToDie after each check.
+	}{
+		{name: "enough lives", numLives: 1, maxChecks: 10, want: []int{90, 80, 70, 60, 50}},
+		{name: "max checks", numLives: 1 << 20, maxChecks: 3, want: []int{100, 100, 90, 90, 90, 80}},
+		{name: "max checks of 1", numLives: 1 << 20, maxChecks: 1, want: []int{90, 80, 70, 60, 50}},
+		{name: "disabled", numLives: 1 << 20, maxChecks: 0, want: []int{100, 100, 100, 100, 100, 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &checkObserver{}
+			cfg := testConfig(t, bomber, bomber) // Both live every few cycles.
+			cfg.CyclesToDie = 100
+			cfg.CycleDelta = 10
+			cfg.NumLives = tt.numLives
+			cfg.MaxChecks = tt.maxChecks
+			cfg.Observer = o
+			cw := newTestCorewar(t, cfg)
+			for len(o.checks) < len(tt.want) {
+				if err := cw.Round(); err != nil {
+					t.Fatalf("Failed to play round at cycle %d: %s.", cw.Cycle, err)
+				}
+			}
+			prev := cfg.CyclesToDie
+			for i, e := range o.checks {
+				if e.CyclesToDie != tt.want[i] {
+					t.Fatalf("CyclesToDie after check %d is %d, expected %d.", i+1, e.CyclesToDie, tt.want[i])
+				}
+				if e.Decreased != (e.CyclesToDie != prev) {
+					t.Fatalf("Check %d decreased: %t, from %d to %d.", i+1, e.Decreased, prev, e.CyclesToDie)
+				}
+				prev = e.CyclesToDie
+			}
+		})
+	}
+}
+
+func TestNewCorewarErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    func(cfg *Config)
+		number int // Player in the error.
+		index  int
+		err    error
+	}{
+		{
+			name:   "duplicate number",
+			cfg:    func(cfg *Config) { cfg.Players[2].Number = 1 },
+			number: 1, index: 2, err: ErrDuplicateNumber,
+		},
+		{
+			name:   "invalid program",
+			cfg:    func(cfg *Config) { cfg.Players[1].Data = []byte("not a champion") },
+			number: 2, index: 1, err: ErrInvalidProgram,
+		},
+		{
+			name: "too large",
+			cfg: func(cfg *Config) {
+				cfg.MemSize = 16
+				cfg.Players = cfg.Players[:1]
+			},
+			number: 1, index: 0, err: ErrProgramTooLarge,
+		},
+		{
+			name:   "overlap",
+			cfg:    func(cfg *Config) { cfg.MemSize = 96 },
+			number: 3, index: 2, err: ErrOverlap,
+		},
+		{
+			name: "overlap sorted",
+			cfg: func(cfg *Config) {
+				cfg.MemSize = 96
+				cfg.Players[1].Number, cfg.Players[2].Number = 3, 2
+			},
+			number: 2, index: 2, err: ErrOverlap,
+		},
+		{
+			name: "original index",
+			cfg: func(cfg *Config) {
+				cfg.Players[0].Number, cfg.Players[2].Number = 3, 1
+				cfg.Players[0].Data = []byte("not a champion")
+			},
+			number: 3, index: 0, err: ErrInvalidProgram,
+		},
+		{
+			name: "champion too large",
+			cfg: func(cfg *Config) {
+				cfg.Players[1].Data = compile(t, ".name \"big\"\n.comment \"too big\"\n"+strings.Repeat("\tlive\t%1\n", op.ChampMaxSize/5+1))
+			},
+			number: 2, index: 1, err: ErrChampionTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t, quitter, quitter, forker)
+			tt.cfg(&cfg)
+			_, err := NewCorewar(cfg)
+			var playerErr *PlayerError
+			if !errors.As(err, &playerErr) {
+				t.Fatalf("Unexpected error: %v, expected a *PlayerError.", err)
+			}
+			if playerErr.Number != tt.number || playerErr.Index != tt.index {
+				t.Fatalf("Error about player %d at index %d, expected player %d at index %d.", playerErr.Number, playerErr.Index, tt.number, tt.index)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Unexpected error: %s, expected %s.", err, tt.err)
+			}
+		})
+	}
+
+	// The players are sorted in a copy.
+	cfg := testConfig(t, quitter, forker)
+	cfg.Players[0].Number = 3
+	if _, err := NewCorewar(cfg); err != nil {
+		t.Fatalf("Failed to create the game: %s.", err)
+	}
+	if cfg.Players[0].Number != 3 || cfg.Players[1].Number != 2 {
+		t.Fatalf("The players of the config got reordered: %d, %d.", cfg.Players[0].Number, cfg.Players[1].Number)
+	}
+
+	if _, err := NewCorewar(testConfig(t)); !errors.Is(err, ErrNoPlayers) {
+		t.Fatalf("Unexpected error without players: %v.", err)
+	}
+}